@@ -5,11 +5,17 @@ It supports pluggable cache stores, cache key generation, and decorators for pag
 package cache
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/gob"
 	"encoding/hex"
 	"io"
 	"log"
+	"maps"
+	"net"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -22,6 +28,8 @@ import (
 
 const (
 	CACHE_MIDDLEWARE_KEY = "gincontrib.cache"
+
+	skipKey = "gincontrib.cache.skip"
 )
 
 var PageCachePrefix = "gincontrib.page.cache"
@@ -33,6 +41,21 @@ type responseCache struct {
 	Status int
 	Header http.Header
 	Data   []byte
+	// Stored is when the response was written to the store.
+	Stored time.Time
+	// Variants is only set on index entries: the request headers, named by the
+	// response's Vary header, that select which variant entry to serve.
+	Variants []string
+	// ETag is the entity tag of the response, either set by the handler or generated from Data.
+	ETag string
+	// Expires is the end of the entry's freshness lifetime. Zero means the entry
+	// is fresh for as long as the store keeps it.
+	Expires time.Time
+	// Encoding is the content coding Data was compressed with, if any.
+	Encoding string
+	// Generations holds the generation of the path, path prefixes and tags the entry was
+	// stored under, keyed by generation key. Bumping or evicting any of them invalidates the entry.
+	Generations map[string]uint64
 }
 
 /*
@@ -45,19 +68,43 @@ func RegisterResponseCacheGob() {
 
 /*
 cachedWriter is a Gin ResponseWriter wrapper that intercepts writes to cache the response.
-It stores the response status, headers, and body, and writes them to the configured cache store.
+It buffers the response body in memory and writes the status, headers, and body to the
+configured cache store in a single commit once the handler has returned.
 */
 type cachedWriter struct {
 	gin.ResponseWriter
-	status  int
-	written bool
-	store   persistence.CacheStore
-	expire  time.Duration
-	key     string
+	status      int
+	written     bool
+	store       persistence.CacheStore
+	expire      time.Duration
+	key         string
+	shouldCache func(status int) bool
+	freshFor    time.Duration
+	etag        bool
+	strip       []string
+	compress    string
+	compressMin int
+	maxBody     int
+	generations map[string]uint64
+	// started is when the handler was started.
+	started   time.Time
+	body      bytes.Buffer
+	discarded bool
+	// held keeps the response away from the client until release is called.
+	held    bool
+	pending bytes.Buffer
+	// bypass reports the request as bypassing the cache when its route opts out.
+	bypass func()
 }
 
 var _ gin.ResponseWriter = &cachedWriter{}
 
+/*
+holdLimit is the size up to which responses are held until the handler returns.
+Larger responses are streamed, without validators on the response that stores them.
+*/
+const holdLimit = 64 << 10
+
 /*
 CreateKey generates a cache key for the given string using the package-specific prefix.
 */
@@ -108,7 +155,13 @@ func generateCacheKey(prefix string, u string) string {
 newCachedWriter constructs a new cachedWriter wrapping the given Gin ResponseWriter.
 */
 func newCachedWriter(store persistence.CacheStore, expire time.Duration, writer gin.ResponseWriter, key string) *cachedWriter {
-	return &cachedWriter{writer, 0, false, store, expire, key}
+	return &cachedWriter{
+		ResponseWriter: writer,
+		store:          store,
+		expire:         expire,
+		key:            key,
+		shouldCache:    defaultShouldCache,
+	}
 }
 
 /*
@@ -135,50 +188,158 @@ func (w *cachedWriter) Written() bool {
 }
 
 /*
-Write writes data to the underlying ResponseWriter and caches the response if status < 300.
-If a previous cache entry exists, it appends the new data to the cached data.
+WriteHeaderNow forces the status and headers to be written, unless the response is held.
 */
-func (w *cachedWriter) Write(data []byte) (int, error) {
-	ret, err := w.ResponseWriter.Write(data)
-	if err == nil {
-		store := w.store
-		var cache responseCache
-		if err := store.Get(w.key, &cache); err == nil {
-			data = append(cache.Data, data...)
-		}
+func (w *cachedWriter) WriteHeaderNow() {
+	if w.held {
+		return
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
 
-		// Cache responses with a status code < 300
-		if w.Status() < 300 {
-			val := responseCache{
-				w.Status(),
-				w.Header(),
-				data,
-			}
-			err = store.Set(w.key, val, w.expire)
-			// if err != nil {
-			// 	// need logger
-			// }
+/*
+Flush sends any buffered data to the client. Flushed responses are streams, so they are
+released if held and never stored.
+*/
+func (w *cachedWriter) Flush() {
+	w.stream()
+	w.ResponseWriter.Flush()
+}
+
+/*
+Hijack hands the connection over to the handler. Hijacked responses are never stored.
+*/
+func (w *cachedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.discard()
+	w.held = false
+	w.pending.Reset()
+	return w.ResponseWriter.Hijack()
+}
+
+/*
+Write writes data to the underlying ResponseWriter and buffers it if the response status is cacheable.
+*/
+func (w *cachedWriter) Write(data []byte) (int, error) {
+	if w.held {
+		w.pending.Write(data)
+		w.buffer(data, nil)
+		if w.held && w.pending.Len() > holdLimit {
+			w.release()
 		}
+		return len(data), nil
 	}
+	ret, err := w.ResponseWriter.Write(data)
+	w.buffer(data[:ret], err)
 	return ret, err
 }
 
 /*
-WriteString writes a string to the underlying ResponseWriter and caches the response if status < 300.
+WriteString writes a string to the underlying ResponseWriter and buffers it if the response status is cacheable.
 */
 func (w *cachedWriter) WriteString(data string) (n int, err error) {
+	if w.held {
+		return w.Write([]byte(data))
+	}
 	ret, err := w.ResponseWriter.WriteString(data)
-	// Cache responses with a status code < 300
-	if err == nil && w.Status() < 300 {
-		store := w.store
-		val := responseCache{
-			w.Status(),
-			w.Header(),
-			[]byte(data),
+	w.buffer([]byte(data[:ret]), err)
+	return ret, err
+}
+
+/*
+release sends a held response to the client.
+*/
+func (w *cachedWriter) release() {
+	w.held = false
+	if w.pending.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.pending.Bytes())
+		w.pending.Reset()
+	}
+}
+
+/*
+skip reports the response of a route that opted out as a bypass, and lets it through to the
+client as it is written.
+*/
+func (w *cachedWriter) skip() {
+	if w.bypass != nil {
+		w.bypass()
+	}
+	w.stream()
+}
+
+/*
+stream gives up on storing the response and lets it through to the client as it is written.
+*/
+func (w *cachedWriter) stream() {
+	w.discard()
+	if w.held {
+		w.release()
+	}
+}
+
+/*
+eventStream reports whether the response is a Server-Sent Events stream.
+*/
+func (w *cachedWriter) eventStream() bool {
+	return strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream")
+}
+
+/*
+buffer appends written data to the in-memory body. A failed write or an uncacheable status
+discards the buffer, since the stored body would no longer match what the handler produced.
+Event streams and bodies growing past the maximum size are streamed instead.
+*/
+func (w *cachedWriter) buffer(data []byte, err error) {
+	if w.discarded {
+		return
+	}
+	if w.eventStream() || (w.maxBody > 0 && w.body.Len()+len(data) > w.maxBody) {
+		w.stream()
+		return
+	}
+	if err != nil || !w.shouldCache(w.Status()) {
+		w.discard()
+		return
+	}
+	w.body.Write(data)
+}
+
+/*
+discard drops the buffered body and prevents the response from being stored.
+*/
+func (w *cachedWriter) discard() {
+	w.discarded = true
+	w.body.Reset()
+}
+
+/*
+commit stores the buffered response with a single Set, unless it was discarded or its status is not cacheable.
+It returns the captured response, or nil if nothing was stored.
+*/
+func (w *cachedWriter) commit() (*responseCache, error) {
+	if w.discarded || !w.shouldCache(w.Status()) || w.eventStream() {
+		return nil, nil
+	}
+	now := time.Now()
+	val := &responseCache{
+		Status:      w.Status(),
+		Header:      w.Header().Clone(),
+		Data:        w.body.Bytes(),
+		Stored:      now,
+		Generations: w.generations,
+	}
+	stripHeaders(val.Header, w.strip)
+	if w.freshFor > 0 {
+		val.Expires = now.Add(w.freshFor)
+	}
+	if w.etag {
+		val.ETag = val.Header.Get("ETag")
+		if val.ETag == "" {
+			val.ETag = generateETag(val.Data)
 		}
-		_ = store.Set(w.key, val, w.expire)
 	}
-	return ret, err
+	val.compress(w.compress, w.compressMin)
+	return val, w.store.Set(w.key, *val, w.expire)
 }
 
 /*
@@ -193,96 +354,439 @@ func Cache(store *persistence.CacheStore) gin.HandlerFunc {
 
 /*
 SiteCache is a Gin middleware that caches entire site responses based on the request URI.
-If a cached response exists, it is written directly; otherwise, the request proceeds as normal.
+If a cached response exists, it is written directly; otherwise, the downstream handlers run and
+their response is stored. Routes can opt out with Skip.
 */
 func SiteCache(store persistence.CacheStore, expire time.Duration) gin.HandlerFunc {
+	return New(store, WithExpire(expire))
+}
+
+/*
+Skip returns a handler that opts the current route out of caching. Register it ahead of the
+route handler when a whole router group is cached with SiteCache or New.
+*/
+func Skip() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var cache responseCache
-		url := c.Request.URL
-		key := CreateKey(url.RequestURI())
-		if err := store.Get(key, &cache); err != nil {
-			c.Next()
-		} else {
-			c.Writer.WriteHeader(cache.Status)
-			for k, vals := range cache.Header {
-				for _, v := range vals {
-					c.Writer.Header().Set(k, v)
-				}
-			}
-			_, _ = c.Writer.Write(cache.Data)
+		c.Set(skipKey, true)
+		// Nothing will be stored, so the response needn't be held back
+		if w, ok := c.Writer.(*cachedWriter); ok {
+			w.skip()
 		}
+		c.Next()
 	}
 }
 
-// CachePage is a decorator that caches the response of the given handler based on the request URI.
-// If a cached response exists, it is served directly. Otherwise, the handler is executed and its response is cached.
-// If the context is aborted, the cache entry is deleted.
-func CachePage(store persistence.CacheStore, expire time.Duration, handle gin.HandlerFunc) gin.HandlerFunc {
+/*
+middleware holds the configuration and per-instance state behind New and Decorate.
+*/
+type middleware struct {
+	store     persistence.CacheStore
+	cfg       *config
+	flights   flightGroup
+	refreshes flightGroup
+	// chained is set for New, where a cache hit must stop the rest of the handler chain.
+	chained bool
+}
+
+/*
+New returns a Gin middleware that caches the responses of the handlers that follow it.
+Behaviour such as the key strategy, header restoration, locking, TTL and status filtering
+is set with options, which can be freely combined.
+*/
+func New(store persistence.CacheStore, opts ...Option) gin.HandlerFunc {
+	m := &middleware{store: store, cfg: newConfig(opts), chained: true}
 	return func(c *gin.Context) {
-		var cache responseCache
-		url := c.Request.URL
-		key := CreateKey(url.RequestURI())
-		if err := store.Get(key, &cache); err != nil {
-			if err != persistence.ErrCacheMiss {
-				log.Println(err.Error())
-			}
-			// Replace writer with cachedWriter to intercept response
-			writer := newCachedWriter(store, expire, c.Writer, key)
-			c.Writer = writer
-			handle(c)
-
-			// Drop caches of aborted contexts
-			if c.IsAborted() {
-				_ = store.Delete(key)
+		m.serve(c, (*gin.Context).Next)
+	}
+}
+
+/*
+Decorate wraps a single handler so that its responses are cached.
+It accepts the same options as New.
+*/
+func Decorate(store persistence.CacheStore, handle gin.HandlerFunc, opts ...Option) gin.HandlerFunc {
+	m := &middleware{store: store, cfg: newConfig(opts)}
+	return func(c *gin.Context) {
+		m.serve(c, handle)
+	}
+}
+
+/*
+serve answers the request from the store when possible. Otherwise it runs handle with a
+cachedWriter in place so that the response is stored. With WithAtomic, concurrent misses
+for the same key wait for a single handler run and share its response.
+*/
+func (m *middleware) serve(c *gin.Context, handle gin.HandlerFunc) {
+	// Skip registered ahead of a decorated handler has already run
+	if c.GetBool(skipKey) || (m.cfg.skip != nil && m.cfg.skip(c)) {
+		m.bypass(c, handle)
+		return
+	}
+	if !m.cfg.cachesMethod(c.Request.Method) {
+		m.bypass(c, handle)
+		return
+	}
+
+	notBefore, ok := m.requestDirectives(c, time.Now())
+	if !ok {
+		m.bypass(c, handle)
+		return
+	}
+
+	raw, ok := m.cfg.keyFunc(c)
+	if ok {
+		raw, ok = m.partitionKey(c, methodKey(c.Request.Method, raw))
+	}
+	if !ok {
+		m.bypass(c, handle)
+		return
+	}
+
+	key := CreateKey(raw)
+	if c.Request.Method == http.MethodHead {
+		m.serveHead(c, handle, key, notBefore)
+		return
+	}
+
+	var cache responseCache
+	var fallback *responseCache
+	if m.lookupEntry(c, key, &cache) {
+		now := time.Now()
+		switch {
+		case cache.Stored.Before(notBefore):
+			// The client refused this entry, so it is only good as a fallback
+		case cache.fresh(now):
+			m.hit(c, &cache)
+			return
+		case cache.staleUsable(now, m.cfg.staleWhileRevalidate):
+			m.serveStale(c, handle, key, &cache)
+			return
+		}
+		fallback = m.fallback(&cache, now)
+	}
+
+	if !m.cfg.atomic {
+		m.run(c, handle, key, fallback)
+		return
+	}
+
+	f, leader := m.flights.join(key)
+	if !leader {
+		<-f.done
+		if f.res != nil && !f.res.varies() {
+			m.hit(c, f.res)
+			return
+		}
+		// The leader may have stored a different variant than this request selects
+		if f.res != nil && m.lookupEntry(c, key, &cache) && cache.fresh(time.Now()) {
+			m.hit(c, &cache)
+			return
+		}
+		// The leader's response was not cacheable, so it can't be shared
+		m.run(c, handle, key, fallback)
+		return
+	}
+
+	var res *responseCache
+	defer func() {
+		m.flights.leave(key, f, res)
+	}()
+
+	// Another request may have stored the entry between the lookup and join
+	var latest responseCache
+	if m.lookupEntry(c, key, &latest) && latest.fresh(time.Now()) && !latest.Stored.Before(notBefore) {
+		res = &latest
+		m.hit(c, res)
+		return
+	}
+
+	if m.cfg.lockTTL > 0 {
+		switch token, err := m.acquireLock(key); err {
+		case nil:
+			defer m.releaseLock(key, token)
+		case persistence.ErrNotStored:
+			// Another process holds the lock, wait for its response
+			if m.waitForEntry(c, key, &latest) {
+				res = &latest
+				m.hit(c, res)
+				return
 			}
+		default:
+			log.Println(err.Error())
+		}
+	}
+
+	res = m.run(c, handle, key, fallback)
+}
+
+/*
+lookup reads the cached response for key into cache and reports whether it was found.
+*/
+func (m *middleware) lookup(key string, cache *responseCache) bool {
+	err := m.store.Get(key, cache)
+	if err != nil && err != persistence.ErrCacheMiss {
+		log.Println(err.Error())
+	}
+	return err == nil
+}
+
+/*
+serveHead answers a HEAD request from the entry stored for GET. Misses run the
+handler without storing its response, which may lack the body GET requests need.
+*/
+func (m *middleware) serveHead(c *gin.Context, handle gin.HandlerFunc, key string, notBefore time.Time) {
+	var cache responseCache
+	if m.lookupEntry(c, key, &cache) && !cache.Stored.Before(notBefore) {
+		now := time.Now()
+		if cache.fresh(now) {
+			m.hit(c, &cache)
+			return
+		}
+		if cache.staleUsable(now, m.cfg.staleWhileRevalidate) {
+			m.hitStale(c, &cache)
+			return
+		}
+	}
+	m.setCacheStatus(c, outcomeMiss, nil)
+	handle(c)
+}
+
+/*
+bypass runs handle without involving the cache.
+*/
+func (m *middleware) bypass(c *gin.Context, handle gin.HandlerFunc) {
+	m.setCacheStatus(c, outcomeBypass, nil)
+	handle(c)
+}
+
+/*
+hit serves a cached response.
+*/
+func (m *middleware) hit(c *gin.Context, cache *responseCache) {
+	m.deliver(c, cache, outcomeHit)
+}
+
+/*
+deliver replays a cached response and, behind New, stops the downstream handlers.
+*/
+func (m *middleware) deliver(c *gin.Context, cache *responseCache, outcome cacheOutcome) {
+	m.replay(c, cache, outcome)
+	if m.chained {
+		c.Abort()
+	}
+}
+
+/*
+run executes handle with a cachedWriter in place and stores the response.
+It returns the stored response, or nil if the response was not cacheable.
+When validators or a fallback entry are in use, responses are held until the handler
+returns, up to holdLimit bytes, so that the ones being stored leave with the validators
+of their entry, and a failed response can be replaced by the fallback if the handler
+returned a 5xx status or aborted.
+*/
+func (m *middleware) run(c *gin.Context, handle gin.HandlerFunc, key string, fallback *responseCache) *responseCache {
+	m.setCacheStatus(c, outcomeMiss, nil)
+	hold := fallback != nil || m.cfg.etag || m.cfg.lastModified
+	writer := m.capture(c, handle, key, hold, func() {
+		m.setCacheStatus(c, outcomeBypass, nil)
+	})
+	// Streamed responses have already reached the client and can't be replaced
+	if fallback != nil && writer.held && (c.IsAborted() || writer.Status() >= http.StatusInternalServerError) {
+		// Drop the failed response, headers included, in favour of the stale entry
+		clear(c.Writer.Header())
+		m.hitStale(c, fallback)
+		return nil
+	}
+
+	// Drop responses of aborted contexts and of routes that opted out
+	if c.GetBool(skipKey) {
+		// Skip may not have reached the writer when other middleware wrapped it
+		m.setCacheStatus(c, outcomeBypass, nil)
+		writer.discard()
+	}
+	if c.IsAborted() {
+		writer.discard()
+	}
+	indexKey, index := m.admit(c, writer)
+	if writer.held {
+		m.addValidators(writer)
+	}
+	writer.release()
+	return m.persist(writer, indexKey, index)
+}
+
+/*
+capture executes handle with a cachedWriter in place and returns the writer holding the response.
+If hold is set, nothing reaches the client until the writer is released. bypass, if set, is called
+when the route opts out with Skip.
+*/
+func (m *middleware) capture(c *gin.Context, handle gin.HandlerFunc, key string, hold bool, bypass func()) *cachedWriter {
+	// Replace writer with cachedWriter to intercept response
+	writer := newCachedWriter(m.store, m.cfg.storeExpire(m.cfg.expire), c.Writer, key)
+	writer.shouldCache = m.cfg.cacheable
+	writer.freshFor = m.cfg.freshFor(m.cfg.expire)
+	writer.etag = m.cfg.etag
+	writer.compress = m.cfg.compression
+	writer.compressMin = m.cfg.compressionThreshold
+	writer.maxBody = m.cfg.maxBodySize
+	writer.strip = slices.Concat(m.cfg.stripHeaders, m.cfg.statusHeaders())
+	writer.held = hold
+	writer.bypass = bypass
+	writer.started = time.Now()
+	// Path generations are read up front, so that invalidations landing while the
+	// handler runs apply to its response
+	if gens, ok := m.generations(m.cfg.pathGenerationKeys(c.Request.URL.Path), writer.started); ok {
+		writer.generations = gens
+	} else {
+		writer.discard()
+	}
+	c.Writer = writer
+	handle(c)
+	c.Writer = writer.ResponseWriter
+	return writer
+}
+
+/*
+commit stores the response held by writer and returns it, or nil if it was not cacheable.
+*/
+func (m *middleware) commit(c *gin.Context, writer *cachedWriter) *responseCache {
+	key, index := m.admit(c, writer)
+	return m.persist(writer, key, index)
+}
+
+/*
+admit decides whether the response held by writer is stored, discarding it otherwise, and
+sets its expiration. Responses with a Vary header are stored as a variant, and admit returns
+the index that leads to it along with the key to store the index at.
+*/
+func (m *middleware) admit(c *gin.Context, writer *cachedWriter) (key string, index *responseCache) {
+	key = writer.key
+	index = m.storeVariant(c, writer)
+
+	// Partial responses to Range requests don't represent the whole resource
+	if c.Request.Header.Get("Range") != "" {
+		writer.discard()
+	}
+
+	ttl := m.cfg.statusExpire(writer.Status())
+	writer.expire = m.cfg.storeExpire(ttl)
+	writer.freshFor = m.cfg.freshFor(ttl)
+	if m.cfg.responseCacheControl {
+		ttl, ok := responseLifetime(writer.Header(), time.Now())
+		if !ok {
+			writer.discard()
+		} else if ttl > 0 {
+			writer.expire = m.cfg.storeExpire(ttl)
+			writer.freshFor = m.cfg.freshFor(ttl)
+		}
+	}
+
+	if m.privateResponse(c, writer.Header()) {
+		writer.discard()
+	}
+	if !writer.discarded {
+		// Tags are only known once the handler returned
+		var tagKeys []string
+		for _, tag := range responseTags(c, writer) {
+			tagKeys = append(tagKeys, tagKey(tag))
+		}
+		if gens, ok := m.generations(tagKeys, writer.started); ok {
+			maps.Copy(writer.generations, gens)
 		} else {
-			c.Writer.WriteHeader(cache.Status)
-			for k, vals := range cache.Header {
-				for _, v := range vals {
-					c.Writer.Header().Set(k, v)
-				}
-			}
-			_, _ = c.Writer.Write(cache.Data)
+			writer.discard()
 		}
 	}
+	if !writer.discarded && m.cfg.negative(writer.Status()) && !m.admitNegative() {
+		writer.discard()
+	}
+	return key, index
 }
 
-// CachePageWithoutQuery is a decorator that caches responses ignoring GET query parameters.
-// The cache key is based only on the request path, so all queries to the same path share the cache.
-func CachePageWithoutQuery(store persistence.CacheStore, expire time.Duration, handle gin.HandlerFunc) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		var cache responseCache
-		key := CreateKey(c.Request.URL.Path)
-		if err := store.Get(key, &cache); err != nil {
-			if err != persistence.ErrCacheMiss {
-				log.Println(err.Error())
+/*
+persist stores the response held by writer, and the Vary index leading to it at key, unless
+admit discarded it. It returns the stored response.
+*/
+func (m *middleware) persist(writer *cachedWriter, key string, index *responseCache) *responseCache {
+	res, err := writer.commit()
+	if err != nil {
+		log.Println(err.Error())
+	}
+	if res != nil && index != nil {
+		if err := m.store.Set(key, *index, writer.expire); err != nil {
+			log.Println(err.Error())
+		}
+	}
+	return res
+}
+
+/*
+replay writes a cached response to the client, or 304 Not Modified when the
+request's validators match the entry. outcome is reported in the cache status headers.
+*/
+func (m *middleware) replay(c *gin.Context, cache *responseCache, outcome cacheOutcome) {
+	compressed := cache.Encoding != ""
+	cache = cache.decodeFor(c.Request)
+	status := cache.Status
+	if m.notModified(c, cache) {
+		status = http.StatusNotModified
+	}
+
+	h := c.Writer.Header()
+	if m.cfg.restoreHeader {
+		for k, vals := range cache.Header {
+			if !m.cfg.replaysHeader(k) {
+				continue
 			}
-			// Replace writer with cachedWriter to intercept response
-			writer := newCachedWriter(store, expire, c.Writer, key)
-			c.Writer = writer
-			handle(c)
-		} else {
-			c.Writer.WriteHeader(cache.Status)
-			for k, vals := range cache.Header {
-				for _, v := range vals {
-					c.Writer.Header().Set(k, v)
-				}
+			h.Del(k)
+			for _, v := range vals {
+				h.Add(k, v)
 			}
-			_, _ = c.Writer.Write(cache.Data)
 		}
 	}
+	if compressed {
+		if names, _ := varyHeaders(h); !slices.Contains(names, "Accept-Encoding") {
+			h.Add("Vary", "Accept-Encoding")
+		}
+		if cache.Encoding != "" {
+			h.Set("Content-Encoding", cache.Encoding)
+			h.Del("Content-Length")
+		}
+	}
+	m.setValidators(h, cache)
+	m.setCacheStatus(c, outcome, cache)
+	bodyless := status == http.StatusNotModified || c.Request.Method == http.MethodHead
+	if status == http.StatusNotModified {
+		h.Del("Content-Length")
+	} else if bodyless && h.Get("Content-Length") == "" {
+		h.Set("Content-Length", strconv.Itoa(len(cache.Data)))
+	}
+
+	// Headers must be in place before the status is written
+	c.Writer.WriteHeader(status)
+	if bodyless {
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	_, _ = c.Writer.Write(cache.Data)
+}
+
+// CachePage is a decorator that caches the response of the given handler based on the request URI.
+// If a cached response exists, it is served directly. Otherwise, the handler is executed and its response is cached.
+// If the context is aborted, the cache entry is deleted.
+func CachePage(store persistence.CacheStore, expire time.Duration, handle gin.HandlerFunc) gin.HandlerFunc {
+	return Decorate(store, handle, WithExpire(expire))
 }
 
-// CachePageAtomic is a decorator that wraps CachePage with a mutex to ensure atomic access.
+// CachePageWithoutQuery is a decorator that caches responses ignoring GET query parameters.
+// The cache key is based only on the request path, so all queries to the same path share the cache.
+func CachePageWithoutQuery(store persistence.CacheStore, expire time.Duration, handle gin.HandlerFunc) gin.HandlerFunc {
+	return Decorate(store, handle, WithExpire(expire), WithoutQuery())
+}
+
+// CachePageAtomic is a decorator that coalesces concurrent cache misses for the same request URI.
 // This prevents concurrent requests from generating duplicate cache entries for the same resource.
 func CachePageAtomic(store persistence.CacheStore, expire time.Duration, handle gin.HandlerFunc) gin.HandlerFunc {
-	var m sync.Mutex
-	p := CachePage(store, expire, handle)
-	return func(c *gin.Context) {
-		m.Lock()
-		defer m.Unlock()
-		p(c)
-	}
+	return Decorate(store, handle, WithExpire(expire), WithAtomic())
 }
 
 /*
@@ -290,26 +794,5 @@ CachePageWithoutHeader is a decorator that caches responses without restoring he
 Only the status and body are restored from the cache.
 */
 func CachePageWithoutHeader(store persistence.CacheStore, expire time.Duration, handle gin.HandlerFunc) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		var cache responseCache
-		url := c.Request.URL
-		key := CreateKey(url.RequestURI())
-		if err := store.Get(key, &cache); err != nil {
-			if err != persistence.ErrCacheMiss {
-				log.Println(err.Error())
-			}
-			// Replace writer with cachedWriter to intercept response
-			writer := newCachedWriter(store, expire, c.Writer, key)
-			c.Writer = writer
-			handle(c)
-
-			// Drop caches of aborted contexts
-			if c.IsAborted() {
-				_ = store.Delete(key)
-			}
-		} else {
-			c.Writer.WriteHeader(cache.Status)
-			_, _ = c.Writer.Write(cache.Data)
-		}
-	}
+	return Decorate(store, handle, WithExpire(expire), WithoutHeader())
 }
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -38,6 +40,48 @@ func TestWrite(t *testing.T) {
 	assert.True(t, c.Writer.Written())
 }
 
+func TestWriteCommit(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	store := newCountingStore(60 * time.Second)
+	writer := newCachedWriter(store, time.Second*3, c.Writer, "mykey")
+	c.Writer = writer
+
+	for i := 0; i < 10; i++ {
+		_, _ = c.Writer.Write([]byte("foo"))
+		_, _ = c.Writer.WriteString("bar")
+	}
+	assert.Equal(t, 0, store.gets+store.sets)
+
+	res, err := writer.commit()
+	assert.NoError(t, err)
+	assert.NotNil(t, res)
+	assert.Equal(t, 0, store.gets)
+	assert.Equal(t, 1, store.sets)
+
+	var cache responseCache
+	assert.NoError(t, store.Get("mykey", &cache))
+	assert.Equal(t, strings.Repeat("foobar", 10), string(cache.Data))
+}
+
+func TestWriteDiscard(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	store := newCountingStore(60 * time.Second)
+	writer := newCachedWriter(store, time.Second*3, c.Writer, "mykey")
+	c.Writer = writer
+
+	c.Writer.WriteHeader(500)
+	_, _ = c.Writer.WriteString("boom")
+	res, err := writer.commit()
+	assert.NoError(t, err)
+	assert.Nil(t, res)
+	assert.Equal(t, 0, store.sets)
+	assert.Equal(t, "boom", w.Body.String())
+}
+
 func TestCachePage(t *testing.T) {
 	store := persistence.NewInMemoryStore(60 * time.Second)
 
@@ -260,9 +304,176 @@ func TestCachePageStatus207(t *testing.T) {
 	time.Sleep(time.Millisecond * 500)
 	w2 := performRequest("GET", "/cache_207", router)
 
+	// 207 is not heuristically cacheable
 	assert.Equal(t, 207, w1.Code)
 	assert.Equal(t, 207, w2.Code)
+	assert.NotEqual(t, w1.Body.String(), w2.Body.String())
+}
+
+func TestCachePageStatus404(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/cache_404", CachePage(store, time.Second*3, func(c *gin.Context) {
+		c.String(404, fmt.Sprint(time.Now().UnixNano()))
+	}))
+
+	w1 := performRequest("GET", "/cache_404", router)
+	time.Sleep(time.Millisecond * 500)
+	w2 := performRequest("GET", "/cache_404", router)
+
+	// Error responses are only stored with WithNegativeCache
+	assert.Equal(t, 404, w1.Code)
+	assert.Equal(t, 404, w2.Code)
+	assert.NotEqual(t, w1.Body.String(), w2.Body.String())
+}
+
+func TestCachePageMultiValueHeaders(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/links", CachePage(store, time.Second*3, func(c *gin.Context) {
+		c.Writer.Header().Add("Link", "</a.css>; rel=preload")
+		c.Writer.Header().Add("Link", "</b.js>; rel=preload")
+		c.String(200, "links")
+	}))
+
+	performRequest("GET", "/links", router)
+	w := performRequest("GET", "/links", router)
+
+	// Result reflects the headers sent along with the status line
+	assert.Equal(t, []string{"</a.css>; rel=preload", "</b.js>; rel=preload"}, w.Result().Header.Values("Link"))
+}
+
+func TestReplayHeaders(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+	handler := func(c *gin.Context) {
+		c.Header("X-Debug", "1")
+		c.Header("X-Version", "2")
+		c.String(200, "ok")
+	}
+
+	router := gin.New()
+	router.GET("/deny", Decorate(store, handler, WithReplayHeaders(nil, []string{"x-debug"})))
+	router.GET("/allow", Decorate(store, handler, WithReplayHeaders([]string{"Content-Type"}, nil)))
+
+	performRequest("GET", "/deny", router)
+	w := performRequest("GET", "/deny", router)
+	assert.Empty(t, w.Header().Get("X-Debug"))
+	assert.Equal(t, "2", w.Header().Get("X-Version"))
+
+	performRequest("GET", "/allow", router)
+	w = performRequest("GET", "/allow", router)
+	assert.Empty(t, w.Header().Get("X-Version"))
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+}
+
+func TestMaxBodySize(t *testing.T) {
+	store := newCountingStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Minute), WithMaxBodySize(16)))
+	router.GET("/small", func(c *gin.Context) {
+		c.String(200, "small")
+	})
+	router.GET("/large", func(c *gin.Context) {
+		for i := 0; i < 10; i++ {
+			_, _ = c.Writer.WriteString("0123456789")
+		}
+	})
+
+	performRequest("GET", "/small", router)
+	assert.Equal(t, 1, store.sets)
+
+	w := performRequest("GET", "/large", router)
+	assert.Equal(t, strings.Repeat("0123456789", 10), w.Body.String())
+	assert.Equal(t, 1, store.sets)
+}
+
+func TestStreamsNotCached(t *testing.T) {
+	store := newCountingStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Minute)))
+	router.GET("/flush", func(c *gin.Context) {
+		c.String(200, "chunk")
+		c.Writer.Flush()
+	})
+	router.GET("/events", func(c *gin.Context) {
+		c.SSEvent("message", "hello")
+	})
+
+	w := performRequest("GET", "/flush", router)
+	assert.Equal(t, "chunk", w.Body.String())
+	assert.True(t, w.Flushed)
+
+	w = performRequest("GET", "/events", router)
+	assert.Contains(t, w.Body.String(), "hello")
+	assert.Equal(t, 0, store.sets)
+}
+
+func TestStreamReleasesHeldResponse(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+	var failing atomic.Bool
+
+	router := gin.New()
+	router.GET("/flush", Decorate(store, func(c *gin.Context) {
+		if failing.Load() {
+			c.String(200, "partial")
+			c.Writer.Flush()
+			c.AbortWithStatus(500)
+			return
+		}
+		c.String(200, "ok")
+	}, WithExpire(time.Second), WithStaleIfError(time.Minute)))
+
+	performRequest("GET", "/flush", router)
+	time.Sleep(time.Millisecond * 1100)
+	failing.Store(true)
+
+	// Flushed data has reached the client, so the stale entry can't replace it
+	w := performRequest("GET", "/flush", router)
+	assert.Equal(t, "partial", w.Body.String())
+}
+
+func TestCachePageRange(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/file", CachePage(store, time.Second*3, func(c *gin.Context) {
+		if c.GetHeader("Range") != "" {
+			c.String(206, "part")
+			return
+		}
+		c.String(200, "whole")
+	}))
+
+	w1 := performRequestWithHeader("GET", "/file", http.Header{"Range": {"bytes=0-3"}}, router)
+	w2 := performRequest("GET", "/file", router)
+
+	assert.Equal(t, 206, w1.Code)
+	assert.Equal(t, 200, w2.Code)
+	assert.Equal(t, "whole", w2.Body.String())
+}
+
+func TestCachePageChunked(t *testing.T) {
+	store := newCountingStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/chunked", CachePage(store, time.Second*3, func(c *gin.Context) {
+		c.Status(200)
+		for i := 0; i < 100; i++ {
+			_, _ = c.Writer.WriteString(fmt.Sprint(i))
+		}
+	}))
+
+	w1 := performRequest("GET", "/chunked", router)
+	w2 := performRequest("GET", "/chunked", router)
+
+	assert.Equal(t, 200, w1.Code)
+	assert.Equal(t, 200, w2.Code)
 	assert.Equal(t, w1.Body.String(), w2.Body.String())
+	assert.Equal(t, 1, store.sets)
 }
 
 func TestCachePageWithoutQuery(t *testing.T) {
@@ -281,6 +492,81 @@ func TestCachePageWithoutQuery(t *testing.T) {
 	assert.Equal(t, w1.Body.String(), w2.Body.String())
 }
 
+func TestSiteCache(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	calls := 0
+	router := gin.New()
+	router.Use(SiteCache(store, time.Second*3))
+	router.GET("/site", func(c *gin.Context) {
+		calls++
+		c.String(200, "pong "+fmt.Sprint(time.Now().UnixNano()))
+	})
+
+	w1 := performRequest("GET", "/site", router)
+	w2 := performRequest("GET", "/site", router)
+
+	assert.Equal(t, 200, w1.Code)
+	assert.Equal(t, 200, w2.Code)
+	assert.Equal(t, w1.Body.String(), w2.Body.String())
+	assert.Equal(t, w1.Header().Get("Content-Type"), w2.Header().Get("Content-Type"))
+	assert.Equal(t, 1, calls)
+}
+
+func TestSiteCache400(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(SiteCache(store, time.Second*3))
+	router.GET("/site_400", func(c *gin.Context) {
+		c.String(400, fmt.Sprint(time.Now().UnixNano()))
+	})
+
+	w1 := performRequest("GET", "/site_400", router)
+	time.Sleep(time.Millisecond * 5)
+	w2 := performRequest("GET", "/site_400", router)
+
+	assert.Equal(t, 400, w1.Code)
+	assert.Equal(t, 400, w2.Code)
+	assert.NotEqual(t, w1.Body.String(), w2.Body.String())
+}
+
+func TestSiteCacheAborted(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(SiteCache(store, time.Second*3))
+	router.GET("/site_aborted", func(c *gin.Context) {
+		c.AbortWithStatusJSON(200, map[string]int64{"time": time.Now().UnixNano()})
+	})
+
+	w1 := performRequest("GET", "/site_aborted", router)
+	time.Sleep(time.Millisecond * 5)
+	w2 := performRequest("GET", "/site_aborted", router)
+
+	assert.Equal(t, 200, w1.Code)
+	assert.Equal(t, 200, w2.Code)
+	assert.NotEqual(t, w1.Body.String(), w2.Body.String())
+}
+
+func TestSiteCacheSkip(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(SiteCache(store, time.Second*3))
+	router.GET("/site_skip", Skip(), func(c *gin.Context) {
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	})
+
+	w1 := performRequest("GET", "/site_skip", router)
+	time.Sleep(time.Millisecond * 5)
+	w2 := performRequest("GET", "/site_skip", router)
+
+	assert.Equal(t, 200, w1.Code)
+	assert.Equal(t, 200, w2.Code)
+	assert.NotEqual(t, w1.Body.String(), w2.Body.String())
+}
+
 func TestRegisterResponseCacheGob(t *testing.T) {
 	RegisterResponseCacheGob()
 	r := responseCache{Status: 200, Data: []byte("test")}
@@ -303,6 +589,14 @@ func performRequest(method, target string, router *gin.Engine) *httptest.Respons
 	return w
 }
 
+func performRequestWithHeader(method, target string, header http.Header, router *gin.Engine) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	r.Header = header
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
 type memoryDelayStore struct {
 	*persistence.InMemoryStore
 }
@@ -322,3 +616,23 @@ func (c *memoryDelayStore) Add(key string, value any, expires time.Duration) err
 	time.Sleep(time.Millisecond * 3)
 	return c.InMemoryStore.Add(key, value, expires)
 }
+
+type countingStore struct {
+	*persistence.InMemoryStore
+	gets int
+	sets int
+}
+
+func newCountingStore(defaultExpiration time.Duration) *countingStore {
+	return &countingStore{InMemoryStore: persistence.NewInMemoryStore(defaultExpiration)}
+}
+
+func (c *countingStore) Get(key string, value any) error {
+	c.gets++
+	return c.InMemoryStore.Get(key, value)
+}
+
+func (c *countingStore) Set(key string, value any, expires time.Duration) error {
+	c.sets++
+	return c.InMemoryStore.Set(key, value, expires)
+}
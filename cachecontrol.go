@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cacheControl holds the directives of a Cache-Control header, keyed by lower-case name.
+type cacheControl map[string]string
+
+// parseCacheControl parses the Cache-Control directives of all given header values.
+func parseCacheControl(values []string) cacheControl {
+	cc := cacheControl{}
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			cc[name] = strings.Trim(strings.TrimSpace(arg), `"`)
+		}
+	}
+	return cc
+}
+
+// has reports whether the directive is present.
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+// seconds returns the delta-seconds argument of the directive.
+func (cc cacheControl) seconds(name string) (time.Duration, bool) {
+	arg, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// responseLifetime returns the freshness lifetime a handler declared with its
+// Cache-Control or Expires headers. ok is false when the response must not be
+// stored; a zero lifetime means the handler didn't declare one.
+func responseLifetime(h http.Header, now time.Time) (lifetime time.Duration, ok bool) {
+	cc := parseCacheControl(h.Values("Cache-Control"))
+	if cc.has("no-store") || cc.has("private") || cc.has("no-cache") {
+		return 0, false
+	}
+
+	// s-maxage is aimed at shared caches and takes precedence over max-age
+	if d, found := cc.seconds("s-maxage"); found {
+		return d, d > 0
+	}
+	if d, found := cc.seconds("max-age"); found {
+		return d, d > 0
+	}
+
+	if v := h.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			// Invalid dates, such as "0", mean the response has already expired
+			return 0, false
+		}
+		if date, err := http.ParseTime(h.Get("Date")); err == nil {
+			now = date
+		}
+		d := expires.Sub(now)
+		if d <= 0 {
+			return 0, false
+		}
+		return d, true
+	}
+	return 0, true
+}
+
+// requestDirectives applies the Cache-Control and Pragma headers of the request.
+// It returns the earliest storage time of an entry the client accepts, and false
+// when the client asked for the cache to be bypassed altogether.
+func (m *middleware) requestDirectives(c *gin.Context, now time.Time) (notBefore time.Time, ok bool) {
+	if !m.cfg.requestCacheControl {
+		return time.Time{}, true
+	}
+
+	cc := parseCacheControl(c.Request.Header.Values("Cache-Control"))
+	if len(cc) == 0 && parseCacheControl(c.Request.Header.Values("Pragma")).has("no-cache") {
+		cc["no-cache"] = ""
+	}
+	if len(cc) == 0 || (m.cfg.allowBypass != nil && !m.cfg.allowBypass(c)) {
+		return time.Time{}, true
+	}
+
+	if cc.has("no-store") {
+		return time.Time{}, false
+	}
+	if cc.has("no-cache") {
+		return now, true
+	}
+	if d, found := cc.seconds("max-age"); found {
+		return now.Add(-d), true
+	}
+	return time.Time{}, true
+}
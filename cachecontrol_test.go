@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCacheControl(t *testing.T) {
+	cc := parseCacheControl([]string{`public, Max-Age=60`, `s-maxage="120", no-transform`})
+
+	assert.True(t, cc.has("public"))
+	assert.True(t, cc.has("no-transform"))
+	assert.False(t, cc.has("private"))
+
+	d, ok := cc.seconds("max-age")
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, d)
+
+	d, ok = cc.seconds("s-maxage")
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, d)
+
+	_, ok = cc.seconds("public")
+	assert.False(t, ok)
+}
+
+func TestResponseLifetime(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		header   http.Header
+		lifetime time.Duration
+		ok       bool
+	}{
+		{http.Header{}, 0, true},
+		{http.Header{"Cache-Control": {"max-age=30"}}, 30 * time.Second, true},
+		{http.Header{"Cache-Control": {"max-age=30, s-maxage=10"}}, 10 * time.Second, true},
+		{http.Header{"Cache-Control": {"max-age=0"}}, 0, false},
+		{http.Header{"Cache-Control": {"no-store"}}, 0, false},
+		{http.Header{"Cache-Control": {"private, max-age=30"}}, 0, false},
+		{http.Header{"Cache-Control": {"no-cache"}}, 0, false},
+		{http.Header{"Expires": {now.Add(time.Hour).Format(http.TimeFormat)}}, time.Hour, true},
+		{http.Header{"Expires": {now.Add(-time.Hour).Format(http.TimeFormat)}}, 0, false},
+		{http.Header{"Expires": {"0"}}, 0, false},
+		{http.Header{
+			"Date":    {now.Add(-time.Minute).Format(http.TimeFormat)},
+			"Expires": {now.Add(time.Minute).Format(http.TimeFormat)},
+		}, 2 * time.Minute, true},
+		{http.Header{
+			"Cache-Control": {"max-age=5"},
+			"Expires":       {now.Add(time.Hour).Format(http.TimeFormat)},
+		}, 5 * time.Second, true},
+	}
+
+	for _, test := range tests {
+		lifetime, ok := responseLifetime(test.header, now)
+		assert.Equal(t, test.ok, ok, test.header)
+		assert.Equal(t, test.lifetime, lifetime, test.header)
+	}
+}
+
+func TestWithResponseCacheControlNoStore(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/no_store", Decorate(store, func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}, WithExpire(time.Second*3), WithResponseCacheControl()))
+
+	w1 := performRequest("GET", "/no_store", router)
+	time.Sleep(time.Millisecond * 5)
+	w2 := performRequest("GET", "/no_store", router)
+
+	assert.NotEqual(t, w1.Body.String(), w2.Body.String())
+}
+
+func TestWithResponseCacheControlMaxAge(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/max_age", Decorate(store, func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=1")
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}, WithExpire(time.Minute), WithResponseCacheControl()))
+
+	w1 := performRequest("GET", "/max_age", router)
+	w2 := performRequest("GET", "/max_age", router)
+	time.Sleep(time.Millisecond * 1100)
+	w3 := performRequest("GET", "/max_age", router)
+
+	assert.Equal(t, w1.Body.String(), w2.Body.String())
+	assert.NotEqual(t, w1.Body.String(), w3.Body.String())
+}
+
+func TestWithoutResponseCacheControl(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/no_store", Decorate(store, func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}, WithExpire(time.Second*3), WithoutPrivacyChecks()))
+
+	w1 := performRequest("GET", "/no_store", router)
+	w2 := performRequest("GET", "/no_store", router)
+
+	assert.Equal(t, w1.Body.String(), w2.Body.String())
+}
+
+func TestWithRequestCacheControl(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/request", Decorate(store, func(c *gin.Context) {
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}, WithExpire(time.Minute), WithRequestCacheControl(nil)))
+
+	w1 := performRequest("GET", "/request", router)
+	w2 := performRequestWithHeader("GET", "/request", http.Header{"Cache-Control": {"max-age=60"}}, router)
+	assert.Equal(t, w1.Body.String(), w2.Body.String())
+
+	// no-store bypasses the cache without touching the entry
+	w3 := performRequestWithHeader("GET", "/request", http.Header{"Cache-Control": {"no-store"}}, router)
+	w4 := performRequest("GET", "/request", router)
+	assert.NotEqual(t, w1.Body.String(), w3.Body.String())
+	assert.Equal(t, w1.Body.String(), w4.Body.String())
+
+	// no-cache refreshes the entry
+	w5 := performRequestWithHeader("GET", "/request", http.Header{"Pragma": {"no-cache"}}, router)
+	w6 := performRequest("GET", "/request", router)
+	assert.NotEqual(t, w1.Body.String(), w5.Body.String())
+	assert.Equal(t, w5.Body.String(), w6.Body.String())
+
+	// max-age refreshes entries older than the given age
+	time.Sleep(time.Millisecond * 1100)
+	w7 := performRequestWithHeader("GET", "/request", http.Header{"Cache-Control": {"max-age=1"}}, router)
+	assert.NotEqual(t, w5.Body.String(), w7.Body.String())
+}
+
+func TestWithRequestCacheControlAllow(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/request", Decorate(store, func(c *gin.Context) {
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}, WithExpire(time.Minute), WithRequestCacheControl(func(c *gin.Context) bool {
+		return c.GetHeader("X-Internal-Token") == "secret"
+	})))
+
+	w1 := performRequest("GET", "/request", router)
+	w2 := performRequestWithHeader("GET", "/request", http.Header{"Cache-Control": {"no-cache"}}, router)
+	w3 := performRequestWithHeader("GET", "/request", http.Header{
+		"Cache-Control":    {"no-cache"},
+		"X-Internal-Token": {"secret"},
+	}, router)
+
+	assert.Equal(t, w1.Body.String(), w2.Body.String())
+	assert.NotEqual(t, w1.Body.String(), w3.Body.String())
+}
+
+func TestWithoutRequestCacheControl(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/request", Decorate(store, func(c *gin.Context) {
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}, WithExpire(time.Minute)))
+
+	w1 := performRequest("GET", "/request", router)
+	w2 := performRequestWithHeader("GET", "/request", http.Header{"Cache-Control": {"no-cache"}}, router)
+
+	assert.Equal(t, w1.Body.String(), w2.Body.String())
+}
@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content codings supported by WithCompression.
+const (
+	EncodingGzip   = "gzip"
+	EncodingZstd   = "zstd"
+	EncodingBrotli = "br"
+)
+
+// errUnsupportedEncoding is returned for content codings other than the Encoding* constants.
+var errUnsupportedEncoding = errors.New("cache: unsupported content encoding")
+
+// zstdEncoder and zstdDecoder are shared, as EncodeAll and DecodeAll are safe for concurrent use.
+var (
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+		return zstd.NewWriter(nil)
+	})
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+		return zstd.NewReader(nil)
+	})
+)
+
+// compressBody encodes data with the given content coding.
+func compressBody(encoding string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case EncodingGzip:
+		w = gzip.NewWriter(&buf)
+	case EncodingBrotli:
+		w = brotli.NewWriter(&buf)
+	case EncodingZstd:
+		enc, err := zstdEncoder()
+		if err != nil {
+			return nil, err
+		}
+		return enc.EncodeAll(data, nil), nil
+	default:
+		return nil, errUnsupportedEncoding
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressBody decodes data encoded with the given content coding.
+func decompressBody(encoding string, data []byte) ([]byte, error) {
+	var r io.Reader
+	switch encoding {
+	case EncodingGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+	case EncodingBrotli:
+		r = brotli.NewReader(bytes.NewReader(data))
+	case EncodingZstd:
+		dec, err := zstdDecoder()
+		if err != nil {
+			return nil, err
+		}
+		return dec.DecodeAll(data, nil)
+	default:
+		return nil, errUnsupportedEncoding
+	}
+	return io.ReadAll(r)
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header of the request
+// allows the given content coding.
+func acceptsEncoding(h http.Header, encoding string) bool {
+	accepted := false
+	for _, value := range h.Values("Accept-Encoding") {
+		for _, part := range strings.Split(value, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name != encoding && name != "*" {
+				continue
+			}
+			q := 1.0
+			if v, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+			// An explicit entry for the coding overrides the wildcard
+			if name == encoding {
+				return q > 0
+			}
+			accepted = q > 0
+		}
+	}
+	return accepted
+}
+
+// encodedETag derives the entity tag of the encoded representation from the one
+// of the identity representation, since the bytes sent differ.
+func encodedETag(etag, encoding string) string {
+	if etag == "" {
+		return ""
+	}
+	return strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+}
+
+// compress encodes the body of the entry if it is large enough and the handler
+// didn't already encode it.
+func (r *responseCache) compress(encoding string, threshold int) {
+	if encoding == "" || len(r.Data) < threshold || r.Header.Get("Content-Encoding") != "" {
+		return
+	}
+	data, err := compressBody(encoding, r.Data)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+	if len(data) < len(r.Data) {
+		r.Data = data
+		r.Encoding = encoding
+	}
+}
+
+// decodeFor returns the entry as it is sent in response to the request: as
+// stored when the client accepts its encoding, decompressed otherwise.
+func (r *responseCache) decodeFor(req *http.Request) *responseCache {
+	if r.Encoding == "" {
+		return r
+	}
+	served := *r
+	if acceptsEncoding(req.Header, r.Encoding) {
+		served.ETag = encodedETag(r.ETag, r.Encoding)
+		return &served
+	}
+	data, err := decompressBody(r.Encoding, r.Data)
+	if err != nil {
+		// Still serve the encoded body; the client may be able to decode it
+		log.Println(err.Error())
+		return &served
+	}
+	served.Data = data
+	served.Encoding = ""
+	return &served
+}
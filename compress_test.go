@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	data := []byte(strings.Repeat("gin-contrib/cache ", 100))
+	for _, encoding := range []string{EncodingGzip, EncodingZstd, EncodingBrotli} {
+		compressed, err := compressBody(encoding, data)
+		assert.NoError(t, err, encoding)
+		assert.Less(t, len(compressed), len(data), encoding)
+
+		decompressed, err := decompressBody(encoding, compressed)
+		assert.NoError(t, err, encoding)
+		assert.Equal(t, data, decompressed, encoding)
+	}
+
+	_, err := compressBody("lz4", data)
+	assert.Equal(t, errUnsupportedEncoding, err)
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	assert.True(t, acceptsEncoding(http.Header{"Accept-Encoding": {"gzip, deflate, br"}}, "br"))
+	assert.True(t, acceptsEncoding(http.Header{"Accept-Encoding": {"*"}}, "zstd"))
+	assert.False(t, acceptsEncoding(http.Header{"Accept-Encoding": {"gzip;q=0, *"}}, "gzip"))
+	assert.False(t, acceptsEncoding(http.Header{"Accept-Encoding": {"identity"}}, "gzip"))
+	assert.False(t, acceptsEncoding(http.Header{}, "gzip"))
+}
+
+func TestWithCompression(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+	body := strings.Repeat("<p>cached page</p>", 100)
+
+	router := gin.New()
+	router.GET("/page", Decorate(store, func(c *gin.Context) {
+		c.String(200, body)
+	}, WithCompression(EncodingGzip, 1024)))
+	router.GET("/small", Decorate(store, func(c *gin.Context) {
+		c.String(200, "small")
+	}, WithCompression(EncodingGzip, 1024)))
+
+	gzipped := http.Header{"Accept-Encoding": {"gzip"}}
+	w1 := performRequestWithHeader("GET", "/page", gzipped, router)
+	assert.Equal(t, body, w1.Body.String())
+
+	var entry responseCache
+	assert.NoError(t, store.Get(CreateKey("/page"), &entry))
+	assert.Equal(t, EncodingGzip, entry.Encoding)
+	assert.Less(t, len(entry.Data), len(body))
+
+	w2 := performRequestWithHeader("GET", "/page", gzipped, router)
+	assert.Equal(t, "gzip", w2.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w2.Header().Get("Vary"))
+	assert.Equal(t, entry.Data, w2.Body.Bytes())
+	assert.NotEqual(t, entry.ETag, w2.Header().Get("ETag"))
+
+	w3 := performRequest("GET", "/page", router)
+	assert.Empty(t, w3.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w3.Header().Get("Vary"))
+	assert.Equal(t, body, w3.Body.String())
+	assert.Equal(t, entry.ETag, w3.Header().Get("ETag"))
+
+	// Bodies below the threshold are stored as is
+	performRequestWithHeader("GET", "/small", gzipped, router)
+	var small responseCache
+	assert.NoError(t, store.Get(CreateKey("/small"), &small))
+	assert.Empty(t, small.Encoding)
+	assert.Empty(t, performRequestWithHeader("GET", "/small", gzipped, router).Header().Get("Content-Encoding"))
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// generateETag returns a strong entity tag for the given body.
+func generateETag(data []byte) string {
+	return `"` + strconv.FormatUint(xxhash.Sum64(data), 16) + `"`
+}
+
+// etagMatch reports whether the If-None-Match header matches etag, using the
+// weak comparison RFC 9110 prescribes for If-None-Match.
+func etagMatch(ifNoneMatch string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// lastModified returns the modification time of the entry: the handler's
+// Last-Modified header if it set one, otherwise the time it was stored.
+func (r *responseCache) lastModified() time.Time {
+	if t, err := http.ParseTime(r.Header.Get("Last-Modified")); err == nil {
+		return t
+	}
+	return r.Stored.Truncate(time.Second)
+}
+
+// setValidators adds the ETag and Last-Modified headers of the entry to h.
+func (m *middleware) setValidators(h http.Header, cache *responseCache) {
+	if m.cfg.etag && cache.ETag != "" {
+		h.Set("ETag", cache.ETag)
+	}
+	if m.cfg.lastModified {
+		if t := cache.lastModified(); !t.IsZero() {
+			h.Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+		}
+	}
+}
+
+// addValidators gives a held response the ETag and Last-Modified headers its entry
+// will carry, so that clients can revalidate from the first response on.
+func (m *middleware) addValidators(w *cachedWriter) {
+	if w.discarded || !w.shouldCache(w.Status()) {
+		return
+	}
+	h := w.Header()
+	if m.cfg.etag && h.Get("ETag") == "" {
+		h.Set("ETag", generateETag(w.body.Bytes()))
+	}
+	if m.cfg.lastModified && h.Get("Last-Modified") == "" {
+		h.Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+	}
+}
+
+// notModified reports whether the request's validators show that the client
+// already holds the cached representation. As RFC 9110 requires, If-Modified-Since
+// is only considered when the request carries no If-None-Match.
+func (m *middleware) notModified(c *gin.Context, cache *responseCache) bool {
+	if cache.Status != http.StatusOK {
+		return false
+	}
+	if ifNoneMatch := c.Request.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return m.cfg.etag && cache.ETag != "" && etagMatch(ifNoneMatch, cache.ETag)
+	}
+	if !m.cfg.lastModified {
+		return false
+	}
+	since, err := http.ParseTime(c.Request.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	t := cache.lastModified()
+	return !t.IsZero() && !t.After(since)
+}
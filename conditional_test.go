@@ -0,0 +1,222 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEtagMatch(t *testing.T) {
+	assert.True(t, etagMatch(`"abc"`, `"abc"`))
+	assert.True(t, etagMatch(`"xyz", W/"abc"`, `"abc"`))
+	assert.True(t, etagMatch(`"abc"`, `W/"abc"`))
+	assert.True(t, etagMatch(`*`, `"abc"`))
+	assert.False(t, etagMatch(`"abcd"`, `"abc"`))
+}
+
+func TestCachePageETag(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/etag", CachePage(store, time.Minute, func(c *gin.Context) {
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}))
+
+	w1 := performRequest("GET", "/etag", router)
+	w2 := performRequest("GET", "/etag", router)
+	etag := w2.Header().Get("ETag")
+	assert.Equal(t, generateETag(w1.Body.Bytes()), etag)
+
+	w3 := performRequestWithHeader("GET", "/etag", http.Header{"If-None-Match": {etag}}, router)
+	assert.Equal(t, http.StatusNotModified, w3.Code)
+	assert.Empty(t, w3.Body.String())
+	assert.Equal(t, etag, w3.Header().Get("ETag"))
+
+	w4 := performRequestWithHeader("GET", "/etag", http.Header{"If-None-Match": {`"stale"`}}, router)
+	assert.Equal(t, 200, w4.Code)
+	assert.Equal(t, w1.Body.String(), w4.Body.String())
+}
+
+func TestValidatorsOnMiss(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(SiteCache(store, time.Minute))
+	router.GET("/etag", func(c *gin.Context) {
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	})
+
+	miss := performRequest("GET", "/etag", router)
+	hit := performRequest("GET", "/etag", router)
+
+	assert.Equal(t, generateETag(miss.Body.Bytes()), miss.Header().Get("ETag"))
+	assert.Equal(t, miss.Header().Get("ETag"), hit.Header().Get("ETag"))
+	assert.NotEmpty(t, miss.Header().Get("Last-Modified"))
+	assert.Equal(t, miss.Header().Get("Last-Modified"), hit.Header().Get("Last-Modified"))
+
+	w := performRequestWithHeader("GET", "/etag", http.Header{"If-None-Match": {miss.Header().Get("ETag")}}, router)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+}
+
+func TestNoValidatorsOnUnstoredMiss(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Minute), WithResponseCacheControl()))
+	router.GET("/skip", Skip(), func(c *gin.Context) {
+		c.String(200, "skipped")
+	})
+	router.GET("/cookie", func(c *gin.Context) {
+		c.SetCookie("theme", "dark", 0, "/", "", false, false)
+		c.String(200, "cookie")
+	})
+	router.GET("/no-store", func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		c.String(200, "no-store")
+	})
+
+	for _, path := range []string{"/skip", "/cookie", "/no-store"} {
+		w := performRequest("GET", path, router)
+		assert.Empty(t, w.Header().Get("ETag"), path)
+		assert.Empty(t, w.Header().Get("Last-Modified"), path)
+	}
+}
+
+func TestMissNotHeldWithoutValidators(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	w := httptest.NewRecorder()
+	var flushed []bool
+	handler := func(c *gin.Context) {
+		c.String(200, "body")
+		flushed = append(flushed, w.Body.Len() > 0)
+	}
+	router := gin.New()
+	router.GET("/plain", Decorate(store, handler, WithoutETag(), WithoutLastModified()))
+	router.GET("/skip", Skip(), Decorate(store, handler))
+	router.GET("/held", Decorate(store, handler))
+
+	for _, path := range []string{"/plain", "/skip", "/held"} {
+		w.Body.Reset()
+		req, _ := http.NewRequestWithContext(context.Background(), "GET", path, nil)
+		router.ServeHTTP(w, req)
+	}
+	assert.Equal(t, []bool{true, true, false}, flushed)
+}
+
+func TestSiteCacheHandlerETag(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(SiteCache(store, time.Minute))
+	router.GET("/etag", func(c *gin.Context) {
+		c.Header("ETag", `"v1"`)
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	})
+
+	performRequest("GET", "/etag", router)
+	w := performRequestWithHeader("GET", "/etag", http.Header{"If-None-Match": {`"v1"`}}, router)
+
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, `"v1"`, w.Header().Get("ETag"))
+}
+
+func TestETagOnlyForOK(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/etag", Decorate(store, func(c *gin.Context) {
+		c.String(404, "missing")
+	}, WithStatusFilter(func(int) bool { return true })))
+
+	w1 := performRequest("GET", "/etag", router)
+	w2 := performRequestWithHeader("GET", "/etag", http.Header{"If-None-Match": {generateETag(w1.Body.Bytes())}}, router)
+
+	assert.Equal(t, 404, w2.Code)
+	assert.Equal(t, "missing", w2.Body.String())
+}
+
+func TestWithoutETag(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/etag", Decorate(store, func(c *gin.Context) {
+		c.String(200, "pong")
+	}, WithoutETag()))
+
+	performRequest("GET", "/etag", router)
+	w := performRequestWithHeader("GET", "/etag", http.Header{"If-None-Match": {generateETag([]byte("pong"))}}, router)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+}
+
+func TestCachePageLastModified(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/last_modified", CachePage(store, time.Minute, func(c *gin.Context) {
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}))
+
+	performRequest("GET", "/last_modified", router)
+	w1 := performRequest("GET", "/last_modified", router)
+	lastModified := w1.Header().Get("Last-Modified")
+	assert.NotEmpty(t, lastModified)
+
+	w2 := performRequestWithHeader("GET", "/last_modified", http.Header{"If-Modified-Since": {lastModified}}, router)
+	assert.Equal(t, http.StatusNotModified, w2.Code)
+	assert.Empty(t, w2.Body.String())
+
+	earlier := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	w3 := performRequestWithHeader("GET", "/last_modified", http.Header{"If-Modified-Since": {earlier}}, router)
+	assert.Equal(t, 200, w3.Code)
+
+	// If-None-Match takes precedence over If-Modified-Since
+	w4 := performRequestWithHeader("GET", "/last_modified", http.Header{
+		"If-None-Match":     {`"other"`},
+		"If-Modified-Since": {lastModified},
+	}, router)
+	assert.Equal(t, 200, w4.Code)
+}
+
+func TestHandlerLastModified(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	modified := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat)
+	router := gin.New()
+	router.GET("/last_modified", Decorate(store, func(c *gin.Context) {
+		c.Header("Last-Modified", modified)
+		c.String(200, "pong")
+	}, WithoutHeader()))
+
+	performRequest("GET", "/last_modified", router)
+	w1 := performRequest("GET", "/last_modified", router)
+	w2 := performRequestWithHeader("GET", "/last_modified", http.Header{"If-Modified-Since": {modified}}, router)
+
+	assert.Equal(t, modified, w1.Header().Get("Last-Modified"))
+	assert.Equal(t, http.StatusNotModified, w2.Code)
+}
+
+func TestWithoutLastModified(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/last_modified", Decorate(store, func(c *gin.Context) {
+		c.String(200, "pong")
+	}, WithoutLastModified()))
+
+	performRequest("GET", "/last_modified", router)
+	now := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	w := performRequestWithHeader("GET", "/last_modified", http.Header{"If-Modified-Since": {now}}, router)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Empty(t, w.Header().Get("Last-Modified"))
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"log"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+)
+
+// generationTTL is how long generations stay in the store. Entries recording a
+// generation that expired are refilled on their next request.
+const generationTTL = 24 * time.Hour
+
+// generation returns the generation stored at key, creating it from seed if
+// missing. Generations hold the time they were created or bumped at, so one
+// evicted from the store never comes back with a value entries recorded.
+func generation(store persistence.CacheStore, key string, seed time.Time) (uint64, error) {
+	var gen uint64
+	if err := store.Get(key, &gen); err != persistence.ErrCacheMiss {
+		return gen, err
+	}
+	gen = uint64(seed.UnixNano())
+	if err := store.Add(key, gen, generationTTL); err != persistence.ErrNotStored {
+		return gen, err
+	}
+	// Another request created the generation concurrently
+	err := store.Get(key, &gen)
+	return gen, err
+}
+
+// bumpGeneration sets the generation stored at key to the current time, so that
+// entries recording an earlier one are no longer current, and requests that
+// started before it don't store their responses.
+func bumpGeneration(store persistence.CacheStore, key string) error {
+	return store.Set(key, uint64(time.Now().UnixNano()), generationTTL)
+}
+
+// generations reads the generation of each key, to be recorded on an entry filled
+// by a request that started at start. ok is false if one can't be read or was
+// bumped after start, in which case the entry must not be stored: it could never
+// be invalidated, or was invalidated while the handler ran.
+func (m *middleware) generations(keys []string, start time.Time) (gens map[string]uint64, ok bool) {
+	gens = make(map[string]uint64, len(keys))
+	for _, key := range keys {
+		gen, err := generation(m.store, key, start)
+		if err != nil {
+			log.Println(err.Error())
+			return nil, false
+		}
+		if gen > uint64(start.UnixNano()) {
+			return nil, false
+		}
+		gens[key] = gen
+	}
+	return gens, true
+}
+
+// current reports whether every generation recorded on the entry is still in the
+// store and hasn't been bumped since it was stored.
+func (m *middleware) current(cache *responseCache) bool {
+	for key, recorded := range cache.Generations {
+		var gen uint64
+		if err := m.store.Get(key, &gen); err != nil {
+			if err != persistence.ErrCacheMiss {
+				log.Println(err.Error())
+			}
+			return false
+		}
+		if gen != recorded {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+)
+
+// Invalidate returns a middleware that evicts cached GET entries once a POST, PUT,
+// PATCH or DELETE request succeeds with a 2xx status. It purges the request path
+// and each of patterns, which are route patterns whose :name and *name segments
+// are filled in from the request's route parameters. A pattern ending in "/*"
+// evicts the whole subtree with PurgePrefix.
+//
+// Every entry for a purged path is evicted, whatever its query string, partition
+// or key function, and so is a response for it that a GET handler is still
+// producing. Subtree patterns only reach middleware using WithPrefixIndex.
+//
+//	r.PUT("/articles/:id", cache.Invalidate(store, "/articles", "/articles/:id/comments/*"), updateArticle)
+func Invalidate(store persistence.CacheStore, patterns ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		status := c.Writer.Status()
+		if !unsafeMethod(c.Request.Method) || c.IsAborted() || status < 200 || status >= 300 {
+			return
+		}
+
+		if err := purgePath(store, c.Request.URL.Path); err != nil {
+			log.Println(err.Error())
+		}
+		for _, pattern := range patterns {
+			path, prefix, ok := expandPattern(pattern, c.Params)
+			if !ok {
+				continue
+			}
+			purge := purgePath
+			if prefix {
+				purge = PurgePrefix
+			}
+			if err := purge(store, path); err != nil {
+				log.Println(err.Error())
+			}
+		}
+	}
+}
+
+// unsafeMethod reports whether method is one of the methods that modify resources.
+func unsafeMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// expandPattern fills in the parameters of a route pattern. prefix reports whether
+// the pattern ends in a "*" wildcard, and ok is false when a parameter is missing.
+func expandPattern(pattern string, params gin.Params) (path string, prefix bool, ok bool) {
+	if trimmed, found := strings.CutSuffix(pattern, "/*"); found {
+		pattern, prefix = trimmed, true
+	}
+
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+			continue
+		}
+		value, found := params.Get(segment[1:])
+		if !found || value == "" {
+			return "", false, false
+		}
+		if segment[0] == '*' {
+			// Catch-all values carry their leading slash
+			value = strings.TrimPrefix(value, "/")
+		}
+		segments[i] = value
+	}
+	path = strings.Join(segments, "/")
+	if path == "" {
+		path = "/"
+	}
+	return path, prefix, true
+}
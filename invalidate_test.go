@@ -0,0 +1,181 @@
+package cache
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidate(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+	body := func(c *gin.Context) {
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}
+
+	router := gin.New()
+	cached := router.Group("/", New(store, WithExpire(time.Minute), WithPrefixIndex()))
+	cached.GET("/articles", body)
+	cached.GET("/articles/:id", body)
+	cached.GET("/articles/:id/comments/:page", body)
+	router.PUT("/articles/:id", Invalidate(store, "/articles", "/articles/:id/comments/*"), func(c *gin.Context) {
+		c.Status(204)
+	})
+	router.DELETE("/articles/:id", Invalidate(store, "/articles"), func(c *gin.Context) {
+		c.AbortWithStatus(404)
+	})
+
+	paths := []string{"/articles", "/articles/7", "/articles/7/comments/1", "/articles/8"}
+	bodies := map[string]string{}
+	for _, path := range paths {
+		bodies[path] = performRequest("GET", path, router).Body.String()
+	}
+
+	// Failed requests don't invalidate anything
+	performRequest("DELETE", "/articles/7", router)
+	for _, path := range paths {
+		assert.Equal(t, bodies[path], performRequest("GET", path, router).Body.String(), path)
+	}
+
+	performRequest("PUT", "/articles/7", router)
+	assert.NotEqual(t, bodies["/articles"], performRequest("GET", "/articles", router).Body.String())
+	assert.NotEqual(t, bodies["/articles/7"], performRequest("GET", "/articles/7", router).Body.String())
+	assert.NotEqual(t, bodies["/articles/7/comments/1"], performRequest("GET", "/articles/7/comments/1", router).Body.String())
+	assert.Equal(t, bodies["/articles/8"], performRequest("GET", "/articles/8", router).Body.String())
+}
+
+func TestExpandPattern(t *testing.T) {
+	params := gin.Params{{Key: "id", Value: "7"}, {Key: "file", Value: "/a/b.txt"}}
+
+	path, prefix, ok := expandPattern("/articles/:id", params)
+	assert.Equal(t, "/articles/7", path)
+	assert.False(t, prefix)
+	assert.True(t, ok)
+
+	path, prefix, ok = expandPattern("/articles/:id/*", params)
+	assert.Equal(t, "/articles/7", path)
+	assert.True(t, prefix)
+	assert.True(t, ok)
+
+	path, _, ok = expandPattern("/files/*file", params)
+	assert.Equal(t, "/files/a/b.txt", path)
+	assert.True(t, ok)
+
+	path, prefix, ok = expandPattern("/*", params)
+	assert.Equal(t, "/", path)
+	assert.True(t, prefix)
+	assert.True(t, ok)
+
+	_, _, ok = expandPattern("/users/:user", params)
+	assert.False(t, ok)
+}
+
+func TestInvalidateQueryVariants(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+	body := func(c *gin.Context) {
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}
+
+	router := gin.New()
+	cached := router.Group("/", New(store, WithExpire(time.Minute), WithPrefixIndex()))
+	cached.GET("/articles", body)
+	cached.GET("/articles/:id", body)
+	router.PUT("/articles/:id", Invalidate(store, "/articles"), func(c *gin.Context) {
+		c.Status(204)
+	})
+
+	paths := []string{"/articles?page=2", "/articles/7?lang=en", "/articles/7", "/articles/8?lang=en"}
+	bodies := map[string]string{}
+	for _, path := range paths {
+		bodies[path] = performRequest("GET", path, router).Body.String()
+	}
+
+	performRequest("PUT", "/articles/7", router)
+
+	assert.NotEqual(t, bodies["/articles?page=2"], performRequest("GET", "/articles?page=2", router).Body.String())
+	assert.NotEqual(t, bodies["/articles/7?lang=en"], performRequest("GET", "/articles/7?lang=en", router).Body.String())
+	assert.NotEqual(t, bodies["/articles/7"], performRequest("GET", "/articles/7", router).Body.String())
+	assert.Equal(t, bodies["/articles/8?lang=en"], performRequest("GET", "/articles/8?lang=en", router).Body.String())
+}
+
+func TestInvalidatePartitions(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", c.Query("user"))
+	})
+	router.GET("/profile", Decorate(store, func(c *gin.Context) {
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}, WithPartition("user"), WithPrefixIndex()))
+	router.POST("/profile", Invalidate(store), func(c *gin.Context) {
+		c.Status(200)
+	})
+
+	alice := performRequest("GET", "/profile?user=alice", router)
+	bob := performRequest("GET", "/profile?user=bob", router)
+
+	performRequest("POST", "/profile", router)
+
+	assert.NotEqual(t, alice.Body.String(), performRequest("GET", "/profile?user=alice", router).Body.String())
+	assert.NotEqual(t, bob.Body.String(), performRequest("GET", "/profile?user=bob", router).Body.String())
+}
+
+func TestInvalidateWithoutPrefixIndex(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/articles/:id", Decorate(store, func(c *gin.Context) {
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}))
+	router.DELETE("/articles/:id", Invalidate(store), func(c *gin.Context) {
+		c.Status(204)
+	})
+
+	bare := performRequest("GET", "/articles/7", router)
+	query := performRequest("GET", "/articles/7?lang=en", router)
+
+	performRequest("DELETE", "/articles/7", router)
+
+	// Entries for the path are reached whatever their query without the prefix index
+	assert.NotEqual(t, bare.Body.String(), performRequest("GET", "/articles/7", router).Body.String())
+	assert.NotEqual(t, query.Body.String(), performRequest("GET", "/articles/7?lang=en", router).Body.String())
+}
+
+func TestInvalidateDuringRender(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	var slow atomic.Bool
+	rendering, invalidated := make(chan struct{}), make(chan struct{})
+	router := gin.New()
+	router.GET("/articles/:id", Decorate(store, func(c *gin.Context) {
+		body := fmt.Sprint(time.Now().UnixNano())
+		if slow.CompareAndSwap(true, false) {
+			close(rendering)
+			<-invalidated
+		}
+		c.String(200, body)
+	}, WithExpire(time.Minute)))
+	router.PUT("/articles/:id", Invalidate(store), func(c *gin.Context) {
+		c.Status(204)
+	})
+
+	// The GET renders the old article, then the PUT succeeds before it returns
+	slow.Store(true)
+	done := make(chan string)
+	go func() {
+		done <- performRequest("GET", "/articles/7", router).Body.String()
+	}()
+	<-rendering
+	performRequest("PUT", "/articles/7", router)
+	close(invalidated)
+	stale := <-done
+
+	w1 := performRequest("GET", "/articles/7", router)
+	assert.NotEqual(t, stale, w1.Body.String())
+	assert.Equal(t, w1.Body.String(), performRequest("GET", "/articles/7", router).Body.String())
+}
@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KeyFunc identifies the cached representation a request maps to. The middleware
+// hashes the returned string into the store key with CreateKey. Returning false
+// skips the cache for the request.
+type KeyFunc func(c *gin.Context) (string, bool)
+
+// methodKey qualifies raw with the request method for methods other than GET and
+// HEAD, which share their entries.
+func methodKey(method, raw string) string {
+	if method == http.MethodGet || method == http.MethodHead {
+		return raw
+	}
+	return method + " " + raw
+}
+
+// RequestURIKey keys requests on the full request URI, query string included.
+// It is the default key function.
+func RequestURIKey(c *gin.Context) (string, bool) {
+	return c.Request.URL.RequestURI(), true
+}
+
+// PathKey keys requests on the request path only.
+func PathKey(c *gin.Context) (string, bool) {
+	return c.Request.URL.Path, true
+}
+
+// SortedQueryKey keys requests on the path and the query parameters in sorted
+// order, so that parameter order doesn't matter.
+func SortedQueryKey(c *gin.Context) (string, bool) {
+	return pathWithQuery(c.Request.URL.Path, normalizeQuery(c.Request.URL.RawQuery, nil, nil)), true
+}
+
+// QueryKey keys requests on the path and the named query parameters, ignoring all others.
+func QueryKey(names ...string) KeyFunc {
+	return NormalizedQueryKey(names, nil)
+}
+
+// NormalizedQueryKey keys requests on the path and a canonical form of the query
+// string: parameters are sorted by name and percent-encoding is normalized, so
+// equivalent URLs share one entry. When allow is not empty, only the parameters
+// it lists are kept; the parameters deny lists are then dropped. Patterns ending
+// in "*" match by prefix, as in "utm_*".
+func NormalizedQueryKey(allow, deny []string) KeyFunc {
+	return func(c *gin.Context) (string, bool) {
+		return pathWithQuery(c.Request.URL.Path, normalizeQuery(c.Request.URL.RawQuery, allow, deny)), true
+	}
+}
+
+// normalizeQuery returns the canonical form of a raw query string, filtered by the
+// allow and deny patterns. Queries that fail to parse are returned unchanged so
+// that malformed requests never share an entry with well-formed ones.
+func normalizeQuery(rawQuery string, allow, deny []string) string {
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return rawQuery
+	}
+	for name := range query {
+		if (len(allow) > 0 && !matchParam(allow, name)) || matchParam(deny, name) {
+			delete(query, name)
+		}
+	}
+	return query.Encode()
+}
+
+// matchParam reports whether name matches one of the patterns.
+func matchParam(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if pattern == name {
+			return true
+		}
+	}
+	return false
+}
+
+// pathWithQuery joins a path and a query string.
+func pathWithQuery(path, query string) string {
+	if query == "" {
+		return path
+	}
+	return path + "?" + query
+}
+
+// HeaderKey keys requests on the values of the named request headers.
+// It is meant to be combined with a path-based key using JoinKeys.
+func HeaderKey(names ...string) KeyFunc {
+	return func(c *gin.Context) (string, bool) {
+		values := url.Values{}
+		for _, name := range names {
+			values[name] = c.Request.Header.Values(name)
+		}
+		return "header:" + values.Encode(), true
+	}
+}
+
+// CookieKey keys requests on the values of the named cookies.
+// It is meant to be combined with a path-based key using JoinKeys.
+func CookieKey(names ...string) KeyFunc {
+	return func(c *gin.Context) (string, bool) {
+		values := url.Values{}
+		for _, name := range names {
+			value, _ := c.Cookie(name)
+			values.Set(name, value)
+		}
+		return "cookie:" + values.Encode(), true
+	}
+}
+
+// ParamKey keys requests on the named route parameters.
+// It is meant to be combined with other keys using JoinKeys.
+func ParamKey(names ...string) KeyFunc {
+	return func(c *gin.Context) (string, bool) {
+		values := url.Values{}
+		for _, name := range names {
+			values.Set(name, c.Param(name))
+		}
+		return "param:" + values.Encode(), true
+	}
+}
+
+// ContextKey keys requests on values set in the gin context, for example by an
+// authentication middleware. Requests missing any of the values are not cached.
+// It is meant to be combined with a path-based key using JoinKeys.
+func ContextKey(keys ...string) KeyFunc {
+	return func(c *gin.Context) (string, bool) {
+		values := url.Values{}
+		for _, key := range keys {
+			value, ok := c.Get(key)
+			if !ok {
+				return "", false
+			}
+			values.Set(key, fmt.Sprint(value))
+		}
+		return "context:" + values.Encode(), true
+	}
+}
+
+// JoinKeys combines several key functions into one. The request is cached only
+// if all of them accept it.
+func JoinKeys(funcs ...KeyFunc) KeyFunc {
+	return func(c *gin.Context) (string, bool) {
+		parts := make([]string, 0, len(funcs))
+		for _, fn := range funcs {
+			part, ok := fn(c)
+			if !ok {
+				return "", false
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, "|"), true
+	}
+}
@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newKeyContext(target string, header http.Header) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", target, nil)
+	for name, values := range header {
+		c.Request.Header[name] = values
+	}
+	return c
+}
+
+func TestKeyFuncs(t *testing.T) {
+	c := newKeyContext("/items?b=2&a=1&utm_source=x", http.Header{
+		"Accept-Language": {"en"},
+		"Cookie":          {"session=abc"},
+	})
+	c.Params = gin.Params{{Key: "id", Value: "42"}}
+	c.Set("user", 7)
+
+	tests := []struct {
+		fn  KeyFunc
+		key string
+	}{
+		{RequestURIKey, "/items?b=2&a=1&utm_source=x"},
+		{PathKey, "/items"},
+		{SortedQueryKey, "/items?a=1&b=2&utm_source=x"},
+		{QueryKey("b", "a", "missing"), "/items?a=1&b=2"},
+		{QueryKey("missing"), "/items"},
+		{HeaderKey("Accept-Language"), "header:Accept-Language=en"},
+		{CookieKey("session"), "cookie:session=abc"},
+		{ParamKey("id"), "param:id=42"},
+		{ContextKey("user"), "context:user=7"},
+		{JoinKeys(PathKey, HeaderKey("Accept-Language")), "/items|header:Accept-Language=en"},
+	}
+
+	for _, test := range tests {
+		key, ok := test.fn(c)
+		assert.True(t, ok)
+		assert.Equal(t, test.key, key)
+	}
+
+	_, ok := ContextKey("tenant")(c)
+	assert.False(t, ok)
+	_, ok = JoinKeys(PathKey, ContextKey("tenant"))(c)
+	assert.False(t, ok)
+}
+
+func TestNormalizedQueryKey(t *testing.T) {
+	tests := []struct {
+		target string
+		allow  []string
+		deny   []string
+		key    string
+	}{
+		{"/items?b=2&a=1", nil, nil, "/items?a=1&b=2"},
+		{"/items?a=%41%20b&b=2", nil, nil, "/items?a=A+b&b=2"},
+		{"/items?a=A+b&b=2", nil, nil, "/items?a=A+b&b=2"},
+		{"/items?a=1&utm_source=x&utm_medium=y", nil, []string{"utm_*"}, "/items?a=1"},
+		{"/items?a=1&b=2&c=3", []string{"a", "c"}, nil, "/items?a=1&c=3"},
+		{"/items?a=1&ab=2&b=3", []string{"a*"}, []string{"ab"}, "/items?a=1"},
+		{"/items?utm_source=x", nil, []string{"utm_*"}, "/items"},
+		{"/items?a=%zz", nil, nil, "/items?a=%zz"},
+	}
+
+	for _, test := range tests {
+		key, ok := NormalizedQueryKey(test.allow, test.deny)(newKeyContext(test.target, nil))
+		assert.True(t, ok)
+		assert.Equal(t, test.key, key, test.target)
+	}
+}
+
+func TestWithKeyFunc(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	calls := 0
+	router := gin.New()
+	router.GET("/items", Decorate(store, func(c *gin.Context) {
+		calls++
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}, WithKeyFunc(func(c *gin.Context) (string, bool) {
+		if c.Query("nocache") != "" {
+			return "", false
+		}
+		return SortedQueryKey(c)
+	})))
+
+	w1 := performRequest("GET", "/items?a=1&b=2", router)
+	w2 := performRequest("GET", "/items?b=2&a=1", router)
+	assert.Equal(t, w1.Body.String(), w2.Body.String())
+	assert.Equal(t, 1, calls)
+
+	performRequest("GET", "/items?nocache=1", router)
+	performRequest("GET", "/items?nocache=1", router)
+	assert.Equal(t, 3, calls)
+}
+
+func TestNormalizedQueryKeySharesEntry(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	calls := 0
+	router := gin.New()
+	router.GET("/items", Decorate(store, func(c *gin.Context) {
+		calls++
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}, WithKeyFunc(NormalizedQueryKey(nil, []string{"utm_*"}))))
+
+	w1 := performRequest("GET", "/items?a=1&b=%32", router)
+	w2 := performRequest("GET", "/items?b=2&utm_campaign=spring&a=1", router)
+
+	assert.Equal(t, w1.Body.String(), w2.Body.String())
+	assert.Equal(t, 1, calls)
+}
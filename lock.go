@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// lockPollInterval is how often a request waiting on another process's lock checks the store.
+const lockPollInterval = 50 * time.Millisecond
+
+// flight is an in-progress handler run for one cache key that other requests can wait on.
+type flight struct {
+	done chan struct{}
+	res  *responseCache
+}
+
+// flightGroup coalesces concurrent cache misses so that each key runs its handler once.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// join returns the flight for key and reports whether the caller leads it.
+func (g *flightGroup) join(key string) (*flight, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if f, ok := g.flights[key]; ok {
+		return f, false
+	}
+	if g.flights == nil {
+		g.flights = make(map[string]*flight)
+	}
+	f := &flight{done: make(chan struct{})}
+	g.flights[key] = f
+	return f, true
+}
+
+// leave publishes the leader's result, which is nil when nothing was cached, and releases the waiters.
+func (g *flightGroup) leave(key string, f *flight, res *responseCache) {
+	g.mu.Lock()
+	delete(g.flights, key)
+	g.mu.Unlock()
+	f.res = res
+	close(f.done)
+}
+
+// lockKey returns the store key of the distributed lock guarding key.
+func lockKey(key string) string {
+	return key + ":lock"
+}
+
+// lockStoreTTL returns the expiration of the distributed lock. Network stores
+// only understand whole seconds, so the TTL is never shorter than a second.
+func (cfg *config) lockStoreTTL() time.Duration {
+	if cfg.lockTTL < time.Second {
+		return time.Second
+	}
+	return cfg.lockTTL
+}
+
+// acquireLock takes the distributed lock guarding key and returns the token
+// identifying this holder.
+func (m *middleware) acquireLock(key string) (string, error) {
+	token := strconv.FormatUint(rand.Uint64(), 36)
+	return token, m.store.Add(lockKey(key), token, m.cfg.lockStoreTTL())
+}
+
+// releaseLock deletes the distributed lock guarding key, unless it expired while
+// the handler ran and another process holds it now.
+func (m *middleware) releaseLock(key, token string) {
+	var holder string
+	if err := m.store.Get(lockKey(key), &holder); err != nil || holder != token {
+		return
+	}
+	_ = m.store.Delete(lockKey(key))
+}
+
+// waitForEntry polls the store until another process has stored a fresh entry for key
+// or the lock TTL elapses.
+func (m *middleware) waitForEntry(c *gin.Context, key string, cache *responseCache) bool {
+	deadline := time.Now().Add(m.cfg.lockTTL)
+	for time.Now().Before(deadline) {
+		time.Sleep(lockPollInterval)
+		if m.lookupEntry(c, key, cache) && cache.fresh(time.Now()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtomicCoalescesSameKey(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	var calls int32
+	router := gin.New()
+	router.GET("/coalesce", Decorate(store, func(c *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(time.Millisecond * 100)
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}, WithExpire(time.Second*5), WithAtomic()))
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 10)
+	for i := range bodies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bodies[i] = performRequest("GET", "/coalesce", router).Body.String()
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, body := range bodies {
+		assert.Equal(t, bodies[0], body)
+	}
+}
+
+func TestAtomicDifferentKeysInParallel(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	// Both handlers must be running at the same time to get past the barrier
+	var barrier sync.WaitGroup
+	barrier.Add(2)
+	router := gin.New()
+	router.GET("/parallel/:id", Decorate(store, func(c *gin.Context) {
+		barrier.Done()
+		barrier.Wait()
+		c.String(200, c.Param("id"))
+	}, WithExpire(time.Second*5), WithAtomic()))
+
+	done := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		for _, id := range []string{"1", "2"} {
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				performRequest("GET", "/parallel/"+id, router)
+			}(id)
+		}
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second * 2):
+		t.Fatal("requests for different keys were serialized")
+	}
+}
+
+func TestDistributedLockWaitsForEntry(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	var calls int32
+	router := gin.New()
+	router.GET("/locked", Decorate(store, func(c *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		c.String(200, "local")
+	}, WithExpire(time.Second*5), WithDistributedLock(time.Second)))
+
+	// Simulate another process holding the lock and storing the response
+	key := CreateKey("/locked")
+	assert.NoError(t, store.Add(lockKey(key), 1, time.Second))
+	go func() {
+		time.Sleep(time.Millisecond * 100)
+		_ = store.Set(key, responseCache{Status: 200, Data: []byte("remote")}, time.Second*5)
+	}()
+
+	w := performRequest("GET", "/locked", router)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "remote", w.Body.String())
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
+
+func TestDistributedLockReleased(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/locked", Decorate(store, func(c *gin.Context) {
+		c.String(200, "local")
+	}, WithExpire(time.Second*5), WithDistributedLock(time.Second)))
+
+	w := performRequest("GET", "/locked", router)
+
+	assert.Equal(t, "local", w.Body.String())
+	var v int
+	assert.Equal(t, persistence.ErrCacheMiss, store.Get(lockKey(CreateKey("/locked")), &v))
+}
+
+func TestDistributedLockKeepsOtherHolder(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/locked", Decorate(store, func(c *gin.Context) {
+		// The lock expires while the handler runs and another process takes it
+		key := lockKey(CreateKey("/locked"))
+		_ = store.Delete(key)
+		_ = store.Add(key, "other", time.Second)
+		c.String(200, "local")
+	}, WithExpire(time.Second*5), WithDistributedLock(time.Second)))
+
+	performRequest("GET", "/locked", router)
+
+	var holder string
+	assert.NoError(t, store.Get(lockKey(CreateKey("/locked")), &holder))
+	assert.Equal(t, "other", holder)
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+)
+
+// negativeCountKey prefixes the store keys counting the negative entries stored in
+// each window.
+var negativeCountKey = generateCacheKey(PageCachePrefix+".negative", "count")
+
+// negativeWindowKey returns the store key counting the negative entries of the
+// window of length ttl that now falls in. Each window has a key of its own, so
+// the limit resets even with stores whose Increment drops the key's expiration.
+func negativeWindowKey(now time.Time, ttl time.Duration) string {
+	return negativeCountKey + ":" + strconv.FormatInt(now.UnixNano()/int64(ttl), 10)
+}
+
+// negative reports whether responses with status go to the negative cache: error
+// responses passing the status filter or listed by WithNegativeCache.
+func (cfg *config) negative(status int) bool {
+	if status < http.StatusBadRequest || cfg.negativeTTL <= 0 {
+		return false
+	}
+	return slices.Contains(cfg.negativeStatuses, status) || cfg.shouldCache(status)
+}
+
+// negativeExpire returns the TTL of negative entries, which never outlive the
+// entries stored for WithExpire.
+func (cfg *config) negativeExpire() time.Duration {
+	if cfg.expire > 0 {
+		return min(cfg.negativeTTL, cfg.expire)
+	}
+	return cfg.negativeTTL
+}
+
+// cacheable reports whether responses with status may be stored. Error responses
+// are only stored in the negative cache.
+func (cfg *config) cacheable(status int) bool {
+	if status >= http.StatusBadRequest {
+		return cfg.negative(status)
+	}
+	return cfg.shouldCache(status)
+}
+
+// admitNegative counts a new negative entry against the limit of the current
+// window, which lasts as long as the entries themselves, and reports whether it
+// may be stored. The count is kept in the store so that every process sharing it
+// respects the limit.
+func (m *middleware) admitNegative() bool {
+	if m.cfg.negativeLimit <= 0 {
+		return true
+	}
+	ttl := m.cfg.negativeExpire()
+	key := negativeWindowKey(time.Now(), ttl)
+	n, err := m.store.Increment(key, 1)
+	if err == persistence.ErrCacheMiss {
+		// Network stores only understand whole seconds
+		n, err = 1, m.store.Add(key, uint64(1), max(ttl, time.Second))
+		if err == persistence.ErrNotStored {
+			// Another request opened the window concurrently
+			n, err = m.store.Increment(key, 1)
+		}
+	}
+	if err != nil {
+		log.Println(err.Error())
+		return false
+	}
+	return n <= uint64(m.cfg.negativeLimit)
+}
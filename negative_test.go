@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegativeCache(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Minute), WithNegativeCache(time.Second, 0, 403)))
+	router.GET("/:status", func(c *gin.Context) {
+		status, _ := strconv.Atoi(c.Param("status"))
+		c.String(status, fmt.Sprint(time.Now().UnixNano()))
+	})
+
+	// Errors from the default status filter and the extra statuses are cached
+	for _, path := range []string{"/404", "/410", "/403"} {
+		w1 := performRequest("GET", path, router)
+		w2 := performRequest("GET", path, router)
+		assert.Equal(t, w1.Code, w2.Code, path)
+		assert.Equal(t, w1.Body.String(), w2.Body.String(), path)
+	}
+
+	// Statuses that aren't listed are not cached
+	w1 := performRequest("GET", "/500", router)
+	w2 := performRequest("GET", "/500", router)
+	assert.NotEqual(t, w1.Body.String(), w2.Body.String())
+
+	// Negative entries use their own TTL rather than WithExpire
+	w1 = performRequest("GET", "/404", router)
+	time.Sleep(time.Second + 100*time.Millisecond)
+	w2 = performRequest("GET", "/404", router)
+	assert.NotEqual(t, w1.Body.String(), w2.Body.String())
+}
+
+func TestNegativeCacheStatuses(t *testing.T) {
+	// Error responses are not stored by default, whatever the status filter says
+	cfg := newConfig(nil)
+	for _, status := range []int{404, 405, 410, 414, 501} {
+		assert.False(t, cfg.cacheable(status), status)
+	}
+	assert.True(t, cfg.cacheable(200))
+
+	cfg = newConfig([]Option{WithExpire(time.Hour), WithNegativeCache(10*time.Second, 0)})
+	for _, status := range []int{404, 405, 410, 414, 501} {
+		assert.True(t, cfg.negative(status), status)
+		assert.Equal(t, 10*time.Second, cfg.statusExpire(status), status)
+	}
+	assert.False(t, cfg.negative(200))
+	assert.Equal(t, time.Hour, cfg.statusExpire(200))
+
+	// Negative entries never outlive the others
+	cfg = newConfig([]Option{WithExpire(time.Second), WithNegativeCache(10*time.Second, 0)})
+	assert.Equal(t, time.Second, cfg.statusExpire(404))
+
+	// A TTL of their own wins
+	cfg = newConfig([]Option{WithStatusTTL(map[int]time.Duration{404: time.Minute}), WithNegativeCache(10*time.Second, 0)})
+	assert.Equal(t, time.Minute, cfg.statusExpire(404))
+}
+
+func TestNegativeCacheLimit(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Minute), WithNegativeCache(time.Minute, 2, 404)))
+	router.GET("/*path", func(c *gin.Context) {
+		c.String(404, fmt.Sprint(time.Now().UnixNano()))
+	})
+
+	for i, cached := range []bool{true, true, false} {
+		path := fmt.Sprintf("/probe-%d", i)
+		w1 := performRequest("GET", path, router)
+		w2 := performRequest("GET", path, router)
+		assert.Equal(t, cached, w1.Body.String() == w2.Body.String(), path)
+	}
+}
+
+// ttlDroppingStore rewrites counters without expiration on Increment, as
+// stores implementing it with a plain GET and SET do.
+type ttlDroppingStore struct {
+	*persistence.InMemoryStore
+}
+
+func (s ttlDroppingStore) Increment(key string, delta uint64) (uint64, error) {
+	var n uint64
+	if err := s.Get(key, &n); err != nil {
+		return 0, err
+	}
+	n += delta
+	return n, s.Set(key, n, persistence.FOREVER)
+}
+
+func TestNegativeCacheLimitResets(t *testing.T) {
+	store := ttlDroppingStore{persistence.NewInMemoryStore(60 * time.Second)}
+
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Minute), WithNegativeCache(time.Second, 1, 404)))
+	router.GET("/*path", func(c *gin.Context) {
+		c.String(404, fmt.Sprint(time.Now().UnixNano()))
+	})
+
+	cached := func(path string) bool {
+		return performRequest("GET", path, router).Body.String() == performRequest("GET", path, router).Body.String()
+	}
+
+	// Start at the beginning of a window so that the first two probes share it
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	assert.True(t, cached("/probe-0"))
+	assert.False(t, cached("/probe-1"))
+
+	time.Sleep(time.Second)
+	assert.True(t, cached("/probe-2"))
+}
@@ -0,0 +1,427 @@
+package cache
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+)
+
+// Option configures the middleware returned by New and Decorate.
+type Option func(*config)
+
+// config holds the settings shared by every request served by one middleware instance.
+type config struct {
+	expire        time.Duration
+	methods       []string
+	keyFunc       KeyFunc
+	partition     string
+	prefixIndex   bool
+	restoreHeader bool
+	replayAllow   []string
+	replayDeny    []string
+	etag          bool
+	lastModified  bool
+	atomic        bool
+	lockTTL       time.Duration
+	shouldCache   func(status int) bool
+	statusTTL     map[int]time.Duration
+	privacyChecks bool
+	stripHeaders  []string
+	skip          func(*gin.Context) bool
+
+	responseCacheControl bool
+	requestCacheControl  bool
+	allowBypass          func(*gin.Context) bool
+	staleWhileRevalidate time.Duration
+	staleIfError         time.Duration
+	staleHeader          string
+	staleHeaderValue     string
+	negativeTTL          time.Duration
+	negativeLimit        int
+	negativeStatuses     []int
+	cacheStatusName      string
+	xCacheHeader         string
+	cacheStatusFilter    func(*gin.Context) bool
+	compression          string
+	compressionThreshold int
+	maxBodySize          int
+}
+
+// newConfig returns the default configuration with the given options applied.
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		expire:        persistence.DEFAULT,
+		methods:       []string{http.MethodGet, http.MethodHead},
+		keyFunc:       RequestURIKey,
+		restoreHeader: true,
+		etag:          true,
+		lastModified:  true,
+		shouldCache:   defaultShouldCache,
+		privacyChecks: true,
+		stripHeaders:  defaultStripHeaders,
+
+		staleHeader:      "Warning",
+		staleHeaderValue: `110 - "Response is Stale"`,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// freshFor returns the freshness lifetime recorded on entries stored for ttl,
+// or zero when entries stay fresh until the store evicts them.
+func (cfg *config) freshFor(ttl time.Duration) time.Duration {
+	if ttl <= 0 || cfg.staleWindow() <= 0 {
+		return 0
+	}
+	return ttl
+}
+
+// staleWindow returns how long entries may be served after their TTL.
+func (cfg *config) staleWindow() time.Duration {
+	return max(cfg.staleWhileRevalidate, cfg.staleIfError)
+}
+
+// storeExpire returns how long entries stored for ttl are kept in the store:
+// their freshness lifetime plus the window during which they may be served stale.
+func (cfg *config) storeExpire(ttl time.Duration) time.Duration {
+	if cfg.freshFor(ttl) == 0 {
+		return ttl
+	}
+	return ttl + cfg.staleWindow()
+}
+
+// replaysHeader reports whether the stored header name is replayed on cache hits.
+func (cfg *config) replaysHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	if len(cfg.replayAllow) > 0 && !slices.Contains(cfg.replayAllow, name) {
+		return false
+	}
+	return !slices.Contains(cfg.replayDeny, name)
+}
+
+// canonicalHeaderKeys returns names in canonical header form.
+func canonicalHeaderKeys(names []string) []string {
+	keys := make([]string, len(names))
+	for i, name := range names {
+		keys[i] = http.CanonicalHeaderKey(name)
+	}
+	return keys
+}
+
+// cachesMethod reports whether requests with method are cached.
+func (cfg *config) cachesMethod(method string) bool {
+	return slices.Contains(cfg.methods, method)
+}
+
+// heuristicallyCacheable lists the status codes RFC 9110 defines as cacheable by default.
+var heuristicallyCacheable = []int{
+	http.StatusOK,
+	http.StatusNonAuthoritativeInfo,
+	http.StatusNoContent,
+	http.StatusPartialContent,
+	http.StatusMultipleChoices,
+	http.StatusMovedPermanently,
+	http.StatusPermanentRedirect,
+	http.StatusNotFound,
+	http.StatusMethodNotAllowed,
+	http.StatusGone,
+	http.StatusRequestURITooLong,
+	http.StatusNotImplemented,
+}
+
+// defaultShouldCache caches responses with a heuristically cacheable status code.
+func defaultShouldCache(status int) bool {
+	return slices.Contains(heuristicallyCacheable, status)
+}
+
+// statusExpire returns the TTL of responses with status. A TTL set by
+// WithStatusTTL wins over the negative cache's.
+func (cfg *config) statusExpire(status int) time.Duration {
+	if ttl := cfg.statusTTL[status]; ttl != 0 {
+		return ttl
+	}
+	if cfg.negative(status) {
+		return cfg.negativeExpire()
+	}
+	return cfg.expire
+}
+
+// WithExpire sets how long a response stays in the store.
+// The default is persistence.DEFAULT, which defers to the store's own expiration.
+func WithExpire(expire time.Duration) Option {
+	return func(cfg *config) {
+		cfg.expire = expire
+	}
+}
+
+// WithMethods sets which request methods are cached; requests with other methods
+// pass straight through to the handler. The default is GET and HEAD. HEAD
+// requests are answered from the entry stored for GET, while other methods get
+// entries of their own.
+func WithMethods(methods ...string) Option {
+	return func(cfg *config) {
+		cfg.methods = methods
+	}
+}
+
+// WithoutQuery keys responses on the request path only, so all query strings
+// for the same path share one cache entry.
+func WithoutQuery() Option {
+	return func(cfg *config) {
+		cfg.keyFunc = PathKey
+	}
+}
+
+// WithKeyFunc sets how requests map to cache entries. See RequestURIKey, the
+// default, and the other key helpers of this package.
+func WithKeyFunc(fn KeyFunc) Option {
+	return func(cfg *config) {
+		cfg.keyFunc = fn
+	}
+}
+
+// WithPartition keeps a separate set of entries per value of contextKey in the gin
+// context, such as a user or tenant ID set by an authentication middleware.
+// Requests without the value are not cached. Use InvalidatePartition to drop the
+// entries of one partition.
+func WithPartition(contextKey string) Option {
+	return func(cfg *config) {
+		cfg.partition = contextKey
+	}
+}
+
+// WithPrefixIndex records on each stored entry the generation of every path
+// prefix it lives under, so that PurgePrefix can evict whole subtrees. Every
+// cache hit then also reads one generation per path segment.
+func WithPrefixIndex() Option {
+	return func(cfg *config) {
+		cfg.prefixIndex = true
+	}
+}
+
+// WithoutHeader replays only the status and body of cached responses,
+// leaving out the stored headers.
+func WithoutHeader() Option {
+	return func(cfg *config) {
+		cfg.restoreHeader = false
+	}
+}
+
+// WithoutPrivacyChecks lets the middleware store responses that set cookies, are
+// marked Cache-Control private or no-store, or answer requests with an
+// Authorization header. Only use it when the cache key already separates users.
+func WithoutPrivacyChecks() Option {
+	return func(cfg *config) {
+		cfg.privacyChecks = false
+	}
+}
+
+// WithStripHeaders sets the headers removed from responses before they are
+// stored. The default is the hop-by-hop headers and Set-Cookie; headers listed in
+// the Connection header are always removed along with them.
+func WithStripHeaders(names ...string) Option {
+	return func(cfg *config) {
+		cfg.stripHeaders = names
+	}
+}
+
+// WithReplayHeaders restricts which stored headers are replayed on cache hits.
+// A non-empty allow replays only the listed headers, and deny is never replayed.
+func WithReplayHeaders(allow, deny []string) Option {
+	return func(cfg *config) {
+		cfg.replayAllow = canonicalHeaderKeys(allow)
+		cfg.replayDeny = canonicalHeaderKeys(deny)
+	}
+}
+
+// WithoutETag stops the middleware from tagging cached responses with an ETag
+// and answering matching If-None-Match requests with 304 Not Modified.
+func WithoutETag() Option {
+	return func(cfg *config) {
+		cfg.etag = false
+	}
+}
+
+// WithoutLastModified stops the middleware from adding a Last-Modified header
+// to cached responses and answering If-Modified-Since requests with 304 Not Modified.
+func WithoutLastModified() Option {
+	return func(cfg *config) {
+		cfg.lastModified = false
+	}
+}
+
+// WithAtomic coalesces concurrent misses for the same cache key: the handler runs
+// once and every waiting request is answered with the captured response.
+// Requests for different keys proceed in parallel.
+func WithAtomic() Option {
+	return func(cfg *config) {
+		cfg.atomic = true
+	}
+}
+
+// WithStatusFilter sets which response status codes are stored. By default
+// responses are stored when their status is heuristically cacheable per RFC 9110:
+// 200, 203, 204, 206, 300, 301, 308, 404, 405, 410, 414 and 501. Error statuses
+// are only stored once WithNegativeCache is set.
+func WithStatusFilter(fn func(status int) bool) Option {
+	return func(cfg *config) {
+		cfg.shouldCache = fn
+	}
+}
+
+// WithStatusTTL stores only responses whose status is listed in ttls, each for
+// its own TTL. A zero TTL falls back to WithExpire, or to the negative cache's
+// TTL for error statuses, which are only stored once WithNegativeCache is set.
+//
+//	cache.WithStatusTTL(map[int]time.Duration{200: 5 * time.Minute, 301: time.Hour, 404: 30 * time.Second})
+func WithStatusTTL(ttls map[int]time.Duration) Option {
+	return func(cfg *config) {
+		cfg.statusTTL = ttls
+		cfg.shouldCache = func(status int) bool {
+			_, ok := ttls[status]
+			return ok
+		}
+	}
+}
+
+// WithNegativeCache stores error responses, which are never stored otherwise.
+// Errors passing the status filter, 404 and 410 among others by default, and
+// those with the extra statuses given are stored for ttl, or WithExpire if
+// shorter. At most limit negative entries are stored per ttl window across every
+// process sharing the store, so that scanners probing random URLs can't fill it;
+// a limit <= 0 means no limit.
+func WithNegativeCache(ttl time.Duration, limit int, statuses ...int) Option {
+	return func(cfg *config) {
+		cfg.negativeTTL = ttl
+		cfg.negativeLimit = limit
+		cfg.negativeStatuses = statuses
+	}
+}
+
+// WithCacheStatus reports how each request was served in an RFC 9211
+// Cache-Status header naming the cache, such as `cacheName; hit; ttl=42`, and
+// adds an Age header to responses served from the store.
+func WithCacheStatus(cacheName string) Option {
+	return func(cfg *config) {
+		cfg.cacheStatusName = cacheName
+	}
+}
+
+// WithXCache reports how each request was served in the named header, X-Cache if
+// empty, as HIT, MISS, STALE or BYPASS, and adds an Age header to responses
+// served from the store.
+func WithXCache(header string) Option {
+	if header == "" {
+		header = "X-Cache"
+	}
+	return func(cfg *config) {
+		cfg.xCacheHeader = header
+	}
+}
+
+// WithCacheStatusFilter limits the headers of WithCacheStatus and WithXCache to
+// requests where fn returns true, for example to keep them off public routes.
+func WithCacheStatusFilter(fn func(c *gin.Context) bool) Option {
+	return func(cfg *config) {
+		cfg.cacheStatusFilter = fn
+	}
+}
+
+// WithCompression stores bodies of at least threshold bytes compressed with
+// encoding: EncodingGzip, EncodingZstd or EncodingBrotli. Clients accepting the
+// encoding receive the compressed bytes with Content-Encoding set; other clients
+// receive the decompressed body. Responses the handler already encoded are stored as is.
+func WithCompression(encoding string, threshold int) Option {
+	return func(cfg *config) {
+		cfg.compression = encoding
+		cfg.compressionThreshold = threshold
+	}
+}
+
+// WithMaxBodySize stops buffering responses once their body grows past size
+// bytes: the entry is dropped and the rest of the response streams straight to
+// the client. Zero, the default, means no limit. Responses that are flushed,
+// hijacked or sent as text/event-stream are never stored regardless.
+func WithMaxBodySize(size int) Option {
+	return func(cfg *config) {
+		cfg.maxBodySize = size
+	}
+}
+
+// WithSkip bypasses the cache entirely for requests where fn returns true,
+// for example to leave some routes of a cached router group alone.
+func WithSkip(fn func(c *gin.Context) bool) Option {
+	return func(cfg *config) {
+		cfg.skip = fn
+	}
+}
+
+// WithDistributedLock extends WithAtomic across processes sharing the store.
+// The request that runs the handler holds a lock taken with CacheStore.Add for at
+// most ttl, while other processes poll the store for the entry for up to ttl
+// before running the handler themselves. The lock is only as exclusive as the
+// store's Add; a holder whose handler outlives ttl leaves the lock to whichever
+// process took it since. Network stores round the lock expiration to whole seconds.
+func WithDistributedLock(ttl time.Duration) Option {
+	return func(cfg *config) {
+		cfg.atomic = true
+		cfg.lockTTL = ttl
+	}
+}
+
+// WithStaleWhileRevalidate keeps entries for window past their TTL, as described
+// in RFC 5861. During that window the stale response is served immediately while
+// one request per key refreshes the entry. It requires a positive WithExpire.
+//
+// Only Decorate can refresh in the background. Behind New and SiteCache the
+// downstream handlers can only run as part of a request, so the one request
+// that refreshes an entry waits for the handlers and receives the fresh
+// response; concurrent requests for the key get the stale one meanwhile.
+func WithStaleWhileRevalidate(window time.Duration) Option {
+	return func(cfg *config) {
+		cfg.staleWhileRevalidate = window
+	}
+}
+
+// WithStaleIfError keeps entries for grace past their TTL, as described in
+// RFC 5861. When the handler for an expired entry fails with a 5xx status or
+// aborts, the stale response is served instead. It requires a positive WithExpire.
+func WithStaleIfError(grace time.Duration) Option {
+	return func(cfg *config) {
+		cfg.staleIfError = grace
+	}
+}
+
+// WithStaleHeader sets the header used to mark stale responses.
+// The default is `Warning: 110 - "Response is Stale"`; an empty name disables the marker.
+func WithStaleHeader(name, value string) Option {
+	return func(cfg *config) {
+		cfg.staleHeader = name
+		cfg.staleHeaderValue = value
+	}
+}
+
+// WithResponseCacheControl lets handlers drive caching with their response headers.
+// Cache-Control no-store, no-cache or private keep the response out of the store,
+// while s-maxage, max-age or Expires override the TTL set with WithExpire.
+func WithResponseCacheControl() Option {
+	return func(cfg *config) {
+		cfg.responseCacheControl = true
+	}
+}
+
+// WithRequestCacheControl honours the client's Cache-Control and Pragma headers:
+// no-store bypasses the cache, no-cache refreshes the entry, and max-age refreshes
+// entries older than the given age. allow restricts which clients may do so;
+// requests it rejects are served as usual. A nil allow trusts every client.
+func WithRequestCacheControl(allow func(c *gin.Context) bool) Option {
+	return func(cfg *config) {
+		cfg.requestCacheControl = true
+		cfg.allowBypass = allow
+	}
+}
@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMiddleware(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	calls := 0
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Second*3)))
+	router.GET("/cache_ping", func(c *gin.Context) {
+		calls++
+		c.String(200, "pong "+fmt.Sprint(time.Now().UnixNano()))
+	})
+
+	w1 := performRequest("GET", "/cache_ping", router)
+	w2 := performRequest("GET", "/cache_ping", router)
+
+	assert.Equal(t, 200, w1.Code)
+	assert.Equal(t, 200, w2.Code)
+	assert.Equal(t, w1.Body.String(), w2.Body.String())
+	assert.Equal(t, 1, calls)
+}
+
+func TestDecorateCombinedOptions(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/combined", Decorate(store, func(c *gin.Context) {
+		c.String(200, "pong "+fmt.Sprint(time.Now().UnixNano()))
+	}, WithExpire(time.Second*3), WithoutQuery(), WithAtomic(), WithoutHeader()))
+
+	w1 := performRequest("GET", "/combined?foo=1", router)
+	w2 := performRequest("GET", "/combined?foo=2", router)
+
+	assert.Equal(t, 200, w1.Code)
+	assert.Equal(t, 200, w2.Code)
+	assert.Equal(t, w1.Body.String(), w2.Body.String())
+	assert.NotNil(t, w1.Header()["Content-Type"])
+	assert.Nil(t, w2.Header()["Content-Type"])
+}
+
+func TestWithStatusFilter(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/cache_404", Decorate(store, func(c *gin.Context) {
+		c.String(404, fmt.Sprint(time.Now().UnixNano()))
+	}, WithStatusFilter(func(status int) bool {
+		return status == 404
+	}), WithNegativeCache(time.Minute, 0)))
+
+	w1 := performRequest("GET", "/cache_404", router)
+	w2 := performRequest("GET", "/cache_404", router)
+
+	assert.Equal(t, 404, w1.Code)
+	assert.Equal(t, 404, w2.Code)
+	assert.Equal(t, w1.Body.String(), w2.Body.String())
+}
+
+func TestWithSkip(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(New(store, WithSkip(func(c *gin.Context) bool {
+		return c.FullPath() == "/live"
+	})))
+	router.GET("/live", func(c *gin.Context) {
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	})
+
+	w1 := performRequest("GET", "/live", router)
+	time.Sleep(time.Millisecond * 5)
+	w2 := performRequest("GET", "/live", router)
+
+	assert.Equal(t, 200, w1.Code)
+	assert.Equal(t, 200, w2.Code)
+	assert.NotEqual(t, w1.Body.String(), w2.Body.String())
+}
+
+func TestWithMethods(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+	handler := func(c *gin.Context) {
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}
+
+	router := gin.New()
+	router.POST("/default", Decorate(store, handler))
+	router.GET("/search", Decorate(store, handler, WithMethods("GET", "POST")))
+	router.POST("/search", Decorate(store, handler, WithMethods("GET", "POST")))
+
+	w1 := performRequest("POST", "/default", router)
+	w2 := performRequest("POST", "/default", router)
+	assert.NotEqual(t, w1.Body.String(), w2.Body.String())
+
+	get := performRequest("GET", "/search", router)
+	post := performRequest("POST", "/search", router)
+	assert.NotEqual(t, get.Body.String(), post.Body.String())
+	assert.Equal(t, get.Body.String(), performRequest("GET", "/search", router).Body.String())
+	assert.Equal(t, post.Body.String(), performRequest("POST", "/search", router).Body.String())
+}
+
+func TestHeadFromGetEntry(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+	calls := 0
+	handler := Decorate(store, func(c *gin.Context) {
+		calls++
+		c.Header("X-Version", "1")
+		c.String(200, "hello")
+	})
+
+	router := gin.New()
+	router.GET("/page", handler)
+	router.HEAD("/page", handler)
+
+	// A HEAD miss runs the handler but doesn't store its response
+	performRequest("HEAD", "/page", router)
+	assert.Equal(t, 1, calls)
+
+	get := performRequest("GET", "/page", router)
+	assert.Equal(t, "hello", get.Body.String())
+	assert.Equal(t, 2, calls)
+
+	head := performRequest("HEAD", "/page", router)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 200, head.Code)
+	assert.Equal(t, "1", head.Header().Get("X-Version"))
+	assert.Equal(t, "5", head.Header().Get("Content-Length"))
+	assert.Empty(t, head.Body.String())
+}
+
+func TestWithStatusTTL(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Minute), WithStatusTTL(map[int]time.Duration{
+		200: 0,
+		404: time.Second,
+		201: time.Minute,
+	})))
+	router.GET("/:status", func(c *gin.Context) {
+		status, _ := strconv.Atoi(c.Param("status"))
+		c.String(status, fmt.Sprint(time.Now().UnixNano()))
+	})
+
+	bodies := map[string]string{}
+	for _, path := range []string{"/200", "/201", "/404", "/301"} {
+		bodies[path] = performRequest("GET", path, router).Body.String()
+	}
+	time.Sleep(time.Second + 100*time.Millisecond)
+
+	assert.Equal(t, bodies["/200"], performRequest("GET", "/200", router).Body.String())
+	assert.Equal(t, bodies["/201"], performRequest("GET", "/201", router).Body.String())
+	assert.NotEqual(t, bodies["/404"], performRequest("GET", "/404", router).Body.String())
+	assert.NotEqual(t, bodies["/301"], performRequest("GET", "/301", router).Body.String())
+}
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+)
+
+// partitionGenerationKey returns the store key holding the generation of a partition.
+// Entries are keyed on the generation, so bumping it orphans all of them at once.
+func partitionGenerationKey(partition string) string {
+	return generateCacheKey(PageCachePrefix+".partition", partition)
+}
+
+// partitionKey namespaces raw with the request's partition and its current generation,
+// created from the current time if missing so that an evicted generation never
+// leads back to a namespace that was invalidated. Requests without a partition
+// value are not cached, so that they can't leak entries.
+func (m *middleware) partitionKey(c *gin.Context, raw string) (string, bool) {
+	if m.cfg.partition == "" {
+		return raw, true
+	}
+	value, ok := c.Get(m.cfg.partition)
+	if !ok {
+		return "", false
+	}
+
+	partition := fmt.Sprint(value)
+	gen, err := generation(m.store, partitionGenerationKey(partition), time.Now())
+	if err != nil {
+		log.Println(err.Error())
+		return "", false
+	}
+	return "partition:" + url.QueryEscape(partition) + "#" + strconv.FormatUint(gen, 10) + "|" + raw, true
+}
+
+// InvalidatePartition drops every entry cached for partition by middleware using
+// WithPartition. The entries are not deleted one by one; they become unreachable
+// and expire from the store on their own.
+func InvalidatePartition(store persistence.CacheStore, partition string) error {
+	return bumpGeneration(store, partitionGenerationKey(partition))
+}
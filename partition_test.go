@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPartitionRouter(store persistence.CacheStore) *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			c.Set("user", user)
+		}
+	})
+	router.GET("/me", Decorate(store, func(c *gin.Context) {
+		c.String(200, c.GetString("user")+" "+fmt.Sprint(time.Now().UnixNano()))
+	}, WithExpire(time.Minute), WithPartition("user")))
+	return router
+}
+
+func TestWithPartition(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+	router := newPartitionRouter(store)
+
+	alice := http.Header{"X-User": {"alice"}}
+	bob := http.Header{"X-User": {"bob"}}
+	w1 := performRequestWithHeader("GET", "/me", alice, router)
+	w2 := performRequestWithHeader("GET", "/me", bob, router)
+	w3 := performRequestWithHeader("GET", "/me", alice, router)
+
+	assert.NotEqual(t, w1.Body.String(), w2.Body.String())
+	assert.Equal(t, w1.Body.String(), w3.Body.String())
+
+	// Anonymous requests are never cached
+	w4 := performRequest("GET", "/me", router)
+	time.Sleep(time.Millisecond * 5)
+	w5 := performRequest("GET", "/me", router)
+	assert.NotEqual(t, w4.Body.String(), w5.Body.String())
+}
+
+func TestInvalidatePartition(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+	router := newPartitionRouter(store)
+
+	alice := http.Header{"X-User": {"alice"}}
+	bob := http.Header{"X-User": {"bob"}}
+	a1 := performRequestWithHeader("GET", "/me", alice, router)
+	b1 := performRequestWithHeader("GET", "/me", bob, router)
+
+	assert.NoError(t, InvalidatePartition(store, "alice"))
+	a2 := performRequestWithHeader("GET", "/me", alice, router)
+	b2 := performRequestWithHeader("GET", "/me", bob, router)
+	assert.NotEqual(t, a1.Body.String(), a2.Body.String())
+	assert.Equal(t, b1.Body.String(), b2.Body.String())
+
+	assert.NoError(t, InvalidatePartition(store, "alice"))
+	a3 := performRequestWithHeader("GET", "/me", alice, router)
+	assert.NotEqual(t, a2.Body.String(), a3.Body.String())
+}
+
+func TestInvalidatePartitionEvicted(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+	router := newPartitionRouter(store)
+
+	alice := http.Header{"X-User": {"alice"}}
+	a1 := performRequestWithHeader("GET", "/me", alice, router)
+	assert.NoError(t, InvalidatePartition(store, "alice"))
+	// The store dropping the bumped generation doesn't bring the old namespace back
+	assert.NoError(t, store.Delete(partitionGenerationKey("alice")))
+
+	a2 := performRequestWithHeader("GET", "/me", alice, router)
+	assert.NotEqual(t, a1.Body.String(), a2.Body.String())
+}
@@ -109,10 +109,31 @@ func (c *RedisStore) Add(key string, value any, expires time.Duration) error {
 			fmt.Printf("Error closing connection: %v\n", err)
 		}
 	}()
-	if exists(conn, key) {
+	switch expires {
+	case DEFAULT:
+		expires = c.defaultExpiration
+	case FOREVER:
+		expires = time.Duration(0)
+	}
+
+	b, err := utils.Serialize(value)
+	if err != nil {
+		return err
+	}
+
+	// SET NX checks and writes the key in one step, so concurrent Adds can't both succeed
+	args := []any{key, b, "NX"}
+	if expires > 0 {
+		args = append(args, "EX", int32(expires/time.Second))
+	}
+	reply, err := conn.Do("SET", args...)
+	if err != nil {
+		return err
+	}
+	if reply == nil {
 		return ErrNotStored
 	}
-	return c.invoke(conn.Do, key, value, expires)
+	return nil
 }
 
 // Replace (see CacheStore interface)
@@ -183,26 +204,55 @@ func (c *RedisStore) Increment(key string, delta uint64) (uint64, error) {
 	}()
 	// Check for existance *before* increment as per the cache contract.
 	// redis will auto create the key, and we don't want that. Since we need to do increment
-	// ourselves instead of natively via INCRBY (redis doesn't support wrapping), we get the value
-	// and do the exists check this way to minimize calls to Redis
-	val, err := conn.Do("GET", key)
-	if val == nil {
-		return 0, ErrCacheMiss
-	}
-	if err == nil {
+	// ourselves instead of natively via INCRBY (redis doesn't support wrapping), the sum is
+	// written back in a transaction that aborts if another client changed the key in the
+	// meantime, with the expiration the key had. KEEPTTL would need redis 6.0.
+	for {
+		if _, err := conn.Do("WATCH", key); err != nil {
+			return 0, err
+		}
+		val, err := conn.Do("GET", key)
+		if err != nil || val == nil {
+			_, _ = conn.Do("UNWATCH")
+			if err == nil {
+				err = ErrCacheMiss
+			}
+			return 0, err
+		}
 		currentVal, err := redis.Int64(val, nil)
 		if err != nil {
+			_, _ = conn.Do("UNWATCH")
+			return 0, err
+		}
+		ttl, err := redis.Int64(conn.Do("PTTL", key))
+		if err != nil || ttl == -2 {
+			// -2 means the key expired since the GET
+			_, _ = conn.Do("UNWATCH")
+			if err == nil {
+				err = ErrCacheMiss
+			}
 			return 0, err
 		}
 		sum := currentVal + int64(delta)
-		_, err = conn.Do("SET", key, sum)
+		args := []any{key, sum}
+		if ttl > 0 {
+			args = append(args, "PX", ttl)
+		}
+		if err := conn.Send("MULTI"); err != nil {
+			return 0, err
+		}
+		if err := conn.Send("SET", args...); err != nil {
+			return 0, err
+		}
+		reply, err := conn.Do("EXEC")
 		if err != nil {
 			return 0, err
 		}
-		return uint64(sum), nil
+		if reply != nil {
+			return uint64(sum), nil
+		}
+		// The key changed after WATCH, retry with its new value
 	}
-
-	return 0, err
 }
 
 // Decrement (see CacheStore interface)
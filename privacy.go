@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultStripHeaders lists the headers removed from responses before they are
+// stored: the hop-by-hop headers of RFC 9110 and Set-Cookie.
+var defaultStripHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"TE",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+	"Set-Cookie",
+}
+
+// stripHeaders removes names from h, along with the headers listed in its
+// Connection header, which are hop-by-hop as well.
+func stripHeaders(h http.Header, names []string) {
+	for _, value := range h.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			h.Del(strings.TrimSpace(name))
+		}
+	}
+	for _, name := range names {
+		h.Del(name)
+	}
+}
+
+// privateResponse reports whether a response must not be shared between clients:
+// it sets a cookie, is marked private or no-store, or answers a request carrying
+// credentials without explicitly allowing shared caching (RFC 9111, section 3.5).
+// Partitioned entries are never shared, so credentials don't matter to them.
+func (m *middleware) privateResponse(c *gin.Context, h http.Header) bool {
+	if !m.cfg.privacyChecks {
+		return false
+	}
+	if len(h.Values("Set-Cookie")) > 0 {
+		return true
+	}
+	cc := parseCacheControl(h.Values("Cache-Control"))
+	if cc.has("private") || cc.has("no-store") {
+		return true
+	}
+	if c.Request.Header.Get("Authorization") != "" && m.cfg.partition == "" {
+		return !cc.has("public") && !cc.has("s-maxage") && !cc.has("must-revalidate")
+	}
+	return false
+}
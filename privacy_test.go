@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrivateResponsesNotStored(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Minute)))
+	router.GET("/cookie", func(c *gin.Context) {
+		c.SetCookie("session", fmt.Sprint(time.Now().UnixNano()), 0, "/", "", false, true)
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	})
+	router.GET("/private", func(c *gin.Context) {
+		c.Header("Cache-Control", "private")
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	})
+	router.GET("/no_store", func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	})
+	router.GET("/account", func(c *gin.Context) {
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	})
+	router.GET("/public", func(c *gin.Context) {
+		c.Header("Cache-Control", "public")
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	})
+
+	for _, path := range []string{"/cookie", "/private", "/no_store"} {
+		w1 := performRequest("GET", path, router)
+		w2 := performRequest("GET", path, router)
+		assert.NotEqual(t, w1.Body.String(), w2.Body.String(), path)
+	}
+
+	auth := http.Header{"Authorization": {"Bearer token"}}
+	w1 := performRequestWithHeader("GET", "/account", auth, router)
+	w2 := performRequestWithHeader("GET", "/account", auth, router)
+	assert.NotEqual(t, w1.Body.String(), w2.Body.String())
+
+	// Responses that explicitly allow shared caching may be stored
+	w1 = performRequestWithHeader("GET", "/public", auth, router)
+	w2 = performRequestWithHeader("GET", "/public", auth, router)
+	assert.Equal(t, w1.Body.String(), w2.Body.String())
+}
+
+func TestWithoutPrivacyChecks(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/cookie", Decorate(store, func(c *gin.Context) {
+		c.SetCookie("theme", "dark", 0, "/", "", false, false)
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}, WithoutPrivacyChecks()))
+
+	w1 := performRequest("GET", "/cookie", router)
+	w2 := performRequest("GET", "/cookie", router)
+
+	assert.Equal(t, w1.Body.String(), w2.Body.String())
+	assert.NotEmpty(t, w1.Header().Get("Set-Cookie"))
+	// Set-Cookie is stripped before storing by default
+	assert.Empty(t, w2.Header().Get("Set-Cookie"))
+}
+
+func TestStripHeaders(t *testing.T) {
+	h := http.Header{
+		"Connection":        {"close, X-Hop"},
+		"X-Hop":             {"1"},
+		"Transfer-Encoding": {"chunked"},
+		"X-Internal":        {"secret"},
+		"Content-Type":      {"text/plain"},
+	}
+	stripHeaders(h, append(defaultStripHeaders, "X-Internal"))
+
+	assert.Equal(t, http.Header{"Content-Type": {"text/plain"}}, h)
+}
+
+func TestStripHeadersEmptyList(t *testing.T) {
+	h := http.Header{
+		"Connection":   {"X-Hop"},
+		"X-Hop":        {"1"},
+		"Content-Type": {"text/plain"},
+	}
+	stripHeaders(h, nil)
+
+	assert.Equal(t, http.Header{"Connection": {"X-Hop"}, "Content-Type": {"text/plain"}}, h)
+}
+
+func TestWithStripHeadersEmpty(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/hop", Decorate(store, func(c *gin.Context) {
+		c.Header("Connection", "X-Hop")
+		c.Header("X-Hop", "1")
+		c.Header("X-Kept", "1")
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}, WithStripHeaders()))
+
+	w1 := performRequest("GET", "/hop", router)
+	w2 := performRequest("GET", "/hop", router)
+
+	assert.Equal(t, w1.Body.String(), w2.Body.String())
+	assert.Equal(t, "1", w2.Header().Get("X-Kept"))
+	// Headers named in Connection are stripped even with an empty list
+	assert.Empty(t, w2.Header().Get("X-Hop"))
+}
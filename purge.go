@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gin-contrib/cache/persistence"
+)
+
+// Purge evicts the entry cached for uri, including all its Vary variants. uri is
+// what the key function returned for the request, which for the default
+// RequestURIKey is the request URI. Purging an entry that isn't cached is not an error.
+func Purge(store persistence.CacheStore, uri string) error {
+	if err := store.Delete(CreateKey(uri)); err != nil && err != persistence.ErrCacheMiss {
+		return err
+	}
+	return nil
+}
+
+// errRootPrefix is returned when purging the root prefix, which entries don't record.
+var errRootPrefix = errors.New("cache: the root prefix can't be purged, flush the store instead")
+
+// PurgePrefix evicts the entries cached for prefix and every path below it by
+// middleware using WithPrefixIndex. Prefixes match whole path segments:
+// "/api/v1/catalog" covers "/api/v1/catalog/items" but not "/api/v1/catalogue".
+// The entries are not deleted one by one; they become unreachable and expire
+// from the store on their own.
+func PurgePrefix(store persistence.CacheStore, prefix string) error {
+	prefix = normalizePrefix(prefix)
+	if prefix == "/" {
+		return errRootPrefix
+	}
+	return bumpGeneration(store, prefixKey(prefix))
+}
+
+// purgePath evicts every entry cached for exactly path, whatever its query
+// string, partition or key function, including those still being filled.
+func purgePath(store persistence.CacheStore, path string) error {
+	path = normalizePrefix(path)
+	return errors.Join(Purge(store, path), bumpGeneration(store, exactPathKey(path)))
+}
+
+// normalizePrefix strips the trailing slash from a path prefix.
+func normalizePrefix(prefix string) string {
+	if prefix = strings.TrimSuffix(prefix, "/"); prefix == "" {
+		return "/"
+	}
+	return prefix
+}
+
+// pathPrefixes returns the prefixes whose generation an entry for path records:
+// each ancestor directory below the root and the path itself.
+func pathPrefixes(path string) []string {
+	path = normalizePrefix(path)
+	var prefixes []string
+	for i := 1; i < len(path); i++ {
+		if path[i] == '/' {
+			prefixes = append(prefixes, path[:i])
+		}
+	}
+	if path != "/" {
+		prefixes = append(prefixes, path)
+	}
+	return prefixes
+}
+
+// pathGenerationKeys returns the keys of the generations an entry for path
+// records: that of the path itself and, with WithPrefixIndex, those of its prefixes.
+func (cfg *config) pathGenerationKeys(path string) []string {
+	keys := []string{exactPathKey(normalizePrefix(path))}
+	if cfg.prefixIndex {
+		for _, prefix := range pathPrefixes(path) {
+			keys = append(keys, prefixKey(prefix))
+		}
+	}
+	return keys
+}
+
+// exactPathKey returns the store key of the generation of the entries for exactly path.
+func exactPathKey(path string) string {
+	return generateCacheKey(PageCachePrefix+".path", path)
+}
+
+// prefixKey returns the store key of the generation of a path prefix.
+func prefixKey(prefix string) string {
+	return generateCacheKey(PageCachePrefix+".prefix", prefix)
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurge(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Minute)))
+	router.GET("/items", func(c *gin.Context) {
+		c.Header("Vary", "Accept")
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	})
+
+	first := performRequest("GET", "/items?page=2", router)
+	other := performRequest("GET", "/items?page=3", router)
+	assert.Equal(t, first.Body.String(), performRequest("GET", "/items?page=2", router).Body.String())
+
+	assert.NoError(t, Purge(store, "/items?page=2"))
+
+	assert.NotEqual(t, first.Body.String(), performRequest("GET", "/items?page=2", router).Body.String())
+	assert.Equal(t, other.Body.String(), performRequest("GET", "/items?page=3", router).Body.String())
+
+	// Purging an entry that isn't cached is not an error
+	assert.NoError(t, Purge(store, "/unknown"))
+}
+
+func TestPurgePrefix(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Minute), WithPrefixIndex()))
+	router.GET("/*path", func(c *gin.Context) {
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	})
+
+	paths := []string{"/api/v1/catalog", "/api/v1/catalog/items?page=2", "/api/v1/catalog/items/3", "/api/v1/catalogue", "/api/v2/catalog"}
+	bodies := map[string]string{}
+	for _, path := range paths {
+		bodies[path] = performRequest("GET", path, router).Body.String()
+	}
+
+	assert.NoError(t, PurgePrefix(store, "/api/v1/catalog/"))
+
+	assert.NotEqual(t, bodies["/api/v1/catalog"], performRequest("GET", "/api/v1/catalog", router).Body.String())
+	assert.NotEqual(t, bodies["/api/v1/catalog/items?page=2"], performRequest("GET", "/api/v1/catalog/items?page=2", router).Body.String())
+	assert.NotEqual(t, bodies["/api/v1/catalog/items/3"], performRequest("GET", "/api/v1/catalog/items/3", router).Body.String())
+	assert.Equal(t, bodies["/api/v1/catalogue"], performRequest("GET", "/api/v1/catalogue", router).Body.String())
+	assert.Equal(t, bodies["/api/v2/catalog"], performRequest("GET", "/api/v2/catalog", router).Body.String())
+
+	// Entries don't record the root prefix
+	assert.Equal(t, errRootPrefix, PurgePrefix(store, "/"))
+}
+
+func TestPurgePrefixDoesNotGrow(t *testing.T) {
+	store := newCountingStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Minute), WithPrefixIndex()))
+	router.GET("/*path", func(c *gin.Context) {
+		c.String(200, "ok")
+	})
+
+	// Only the entries themselves are written, however many are stored
+	for i := 0; i < 100; i++ {
+		performRequest("GET", fmt.Sprintf("/a/b/%d", i), router)
+	}
+	assert.Equal(t, 100, store.sets)
+}
+
+func TestPathPrefixes(t *testing.T) {
+	assert.Empty(t, pathPrefixes("/"))
+	assert.Equal(t, []string{"/a", "/a/b", "/a/b/c"}, pathPrefixes("/a/b/c"))
+	assert.Equal(t, []string{"/a", "/a/b"}, pathPrefixes("/a/b/"))
+}
@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errNoConnection is returned when hijacking a writer that has no client behind it.
+var errNoConnection = errors.New("cache: response writer has no connection")
+
+// fresh reports whether the entry is still within its freshness lifetime at now.
+func (r *responseCache) fresh(now time.Time) bool {
+	return r.Expires.IsZero() || now.Before(r.Expires)
+}
+
+// staleUsable reports whether a stale entry may still be served at now, given the window
+// after expiry during which stale responses are acceptable.
+func (r *responseCache) staleUsable(now time.Time, window time.Duration) bool {
+	return !r.Expires.IsZero() && now.Before(r.Expires.Add(window))
+}
+
+// fallback returns cache if it may stand in for a failed response at now, nil otherwise.
+func (m *middleware) fallback(cache *responseCache, now time.Time) *responseCache {
+	if cache.staleUsable(now, m.cfg.staleIfError) {
+		return cache
+	}
+	return nil
+}
+
+// hitStale serves a stale entry, marked with the configured stale header.
+func (m *middleware) hitStale(c *gin.Context, cache *responseCache) {
+	if m.cfg.staleHeader != "" {
+		c.Writer.Header().Set(m.cfg.staleHeader, m.cfg.staleHeaderValue)
+	}
+	m.deliver(c, cache, outcomeStale)
+}
+
+// serveStale answers the request with a stale entry and makes sure one request
+// per key refreshes it. Decorated handlers are re-run in the background; behind
+// New the downstream handlers can only run as part of the request, so the
+// refreshing request runs them in the foreground instead.
+func (m *middleware) serveStale(c *gin.Context, handle gin.HandlerFunc, key string, cache *responseCache) {
+	f, leader := m.refreshes.join(key)
+	if !leader {
+		m.hitStale(c, cache)
+		return
+	}
+
+	if m.chained {
+		var res *responseCache
+		defer func() {
+			m.refreshes.leave(key, f, res)
+		}()
+		res = m.run(c, handle, key, m.fallback(cache, time.Now()))
+		return
+	}
+
+	m.hitStale(c, cache)
+	cp := c.Copy()
+	cp.Request = c.Request.WithContext(context.WithoutCancel(c.Request.Context()))
+	go m.refresh(cp, handle, key, f)
+}
+
+// refresh re-runs handle on a copy of the request context and stores the new response.
+func (m *middleware) refresh(c *gin.Context, handle gin.HandlerFunc, key string, f *flight) {
+	var res *responseCache
+	defer func() {
+		if err := recover(); err != nil {
+			log.Printf("cache: revalidating %s panicked: %v", c.Request.URL.Path, err)
+		}
+		m.refreshes.leave(key, f, res)
+	}()
+
+	c.Writer = newDiscardWriter()
+	writer := m.capture(c, handle, key, false, nil)
+	// Copied contexts always report being aborted, so only the skip flag is honoured
+	if c.GetBool(skipKey) {
+		writer.discard()
+	}
+	res = m.commit(c, writer)
+}
+
+// discardWriter is a gin.ResponseWriter with no client behind it, used to run
+// handlers outside of a request.
+type discardWriter struct {
+	header http.Header
+	status int
+	size   int
+}
+
+var _ gin.ResponseWriter = &discardWriter{}
+
+func newDiscardWriter() *discardWriter {
+	return &discardWriter{header: http.Header{}, status: http.StatusOK, size: -1}
+}
+
+func (w *discardWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardWriter) WriteHeader(code int) {
+	if code > 0 && !w.Written() {
+		w.status = code
+	}
+}
+
+func (w *discardWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.size = 0
+	}
+}
+
+func (w *discardWriter) Write(data []byte) (int, error) {
+	w.WriteHeaderNow()
+	w.size += len(data)
+	return len(data), nil
+}
+
+func (w *discardWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *discardWriter) Status() int {
+	return w.status
+}
+
+func (w *discardWriter) Size() int {
+	return w.size
+}
+
+func (w *discardWriter) Written() bool {
+	return w.size != -1
+}
+
+func (w *discardWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errNoConnection
+}
+
+func (w *discardWriter) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+func (w *discardWriter) Flush() {}
+
+func (w *discardWriter) Pusher() http.Pusher {
+	return nil
+}
@@ -0,0 +1,174 @@
+package cache
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	var calls int32
+	router := gin.New()
+	router.GET("/swr", Decorate(store, func(c *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}, WithExpire(time.Millisecond*200), WithStaleWhileRevalidate(time.Second*5)))
+
+	w1 := performRequest("GET", "/swr", router)
+	time.Sleep(time.Millisecond * 300)
+	w2 := performRequest("GET", "/swr", router)
+	time.Sleep(time.Millisecond * 100)
+	w3 := performRequest("GET", "/swr", router)
+
+	assert.Equal(t, 200, w2.Code)
+	assert.Equal(t, w1.Body.String(), w2.Body.String())
+	assert.NotEqual(t, w2.Body.String(), w3.Body.String())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestStaleWhileRevalidateExpired(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/swr", Decorate(store, func(c *gin.Context) {
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}, WithExpire(time.Millisecond*100), WithStaleWhileRevalidate(time.Millisecond*100)))
+
+	w1 := performRequest("GET", "/swr", router)
+	time.Sleep(time.Millisecond * 300)
+	w2 := performRequest("GET", "/swr", router)
+
+	assert.NotEqual(t, w1.Body.String(), w2.Body.String())
+}
+
+func TestStaleWhileRevalidateMiddleware(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Millisecond*200), WithStaleWhileRevalidate(time.Second*5)))
+	router.GET("/swr", func(c *gin.Context) {
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	})
+
+	w1 := performRequest("GET", "/swr", router)
+	time.Sleep(time.Millisecond * 300)
+	w2 := performRequest("GET", "/swr", router)
+	w3 := performRequest("GET", "/swr", router)
+
+	assert.NotEqual(t, w1.Body.String(), w2.Body.String())
+	assert.Equal(t, w2.Body.String(), w3.Body.String())
+}
+
+func TestStaleWhileRevalidateIgnoresAbortedCopy(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/swr", Decorate(store, func(c *gin.Context) {
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}, WithExpire(time.Millisecond*200), WithStaleWhileRevalidate(time.Second*5)))
+
+	performRequest("GET", "/swr", router)
+	time.Sleep(time.Millisecond * 300)
+	performRequest("GET", "/swr", router)
+	time.Sleep(time.Millisecond * 100)
+
+	var cache responseCache
+	assert.NoError(t, store.Get(CreateKey("/swr"), &cache))
+	assert.True(t, cache.fresh(time.Now()))
+}
+
+func TestStaleIfError(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	var failing atomic.Bool
+	router := gin.New()
+	router.GET("/sie", Decorate(store, func(c *gin.Context) {
+		if failing.Load() {
+			c.Header("X-Failure", "upstream")
+			c.String(502, "bad gateway")
+			return
+		}
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}, WithExpire(time.Millisecond*100), WithStaleIfError(time.Second*5)))
+
+	w1 := performRequest("GET", "/sie", router)
+	time.Sleep(time.Millisecond * 200)
+	failing.Store(true)
+	w2 := performRequest("GET", "/sie", router)
+
+	assert.Equal(t, 200, w2.Code)
+	assert.Equal(t, w1.Body.String(), w2.Body.String())
+	assert.Equal(t, `110 - "Response is Stale"`, w2.Header().Get("Warning"))
+	assert.Empty(t, w2.Header().Get("X-Failure"))
+}
+
+func TestStaleIfErrorAborted(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	var failing atomic.Bool
+	router := gin.New()
+	router.GET("/sie", Decorate(store, func(c *gin.Context) {
+		if failing.Load() {
+			c.AbortWithStatus(503)
+			return
+		}
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}, WithExpire(time.Millisecond*100), WithStaleIfError(time.Second*5), WithStaleHeader("X-Cache-Stale", "1")))
+
+	w1 := performRequest("GET", "/sie", router)
+	time.Sleep(time.Millisecond * 200)
+	failing.Store(true)
+	w2 := performRequest("GET", "/sie", router)
+
+	assert.Equal(t, 200, w2.Code)
+	assert.Equal(t, w1.Body.String(), w2.Body.String())
+	assert.Equal(t, "1", w2.Header().Get("X-Cache-Stale"))
+	assert.Empty(t, w2.Header().Get("Warning"))
+}
+
+func TestStaleIfErrorPassesClientErrors(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	var failing atomic.Bool
+	router := gin.New()
+	router.GET("/sie", Decorate(store, func(c *gin.Context) {
+		if failing.Load() {
+			c.String(404, "gone")
+			return
+		}
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}, WithExpire(time.Millisecond*100), WithStaleIfError(time.Second*5)))
+
+	performRequest("GET", "/sie", router)
+	time.Sleep(time.Millisecond * 200)
+	failing.Store(true)
+	w2 := performRequest("GET", "/sie", router)
+
+	assert.Equal(t, 404, w2.Code)
+	assert.Equal(t, "gone", w2.Body.String())
+}
+
+func TestStaleIfErrorRefreshes(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/sie", Decorate(store, func(c *gin.Context) {
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}, WithExpire(time.Millisecond*100), WithStaleIfError(time.Second*5)))
+
+	w1 := performRequest("GET", "/sie", router)
+	time.Sleep(time.Millisecond * 200)
+	w2 := performRequest("GET", "/sie", router)
+	w3 := performRequest("GET", "/sie", router)
+
+	assert.NotEqual(t, w1.Body.String(), w2.Body.String())
+	assert.Equal(t, w2.Body.String(), w3.Body.String())
+	assert.Empty(t, w2.Header().Get("Warning"))
+}
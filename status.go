@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cacheOutcome describes how the middleware answered a request.
+type cacheOutcome string
+
+const (
+	outcomeHit    cacheOutcome = "HIT"
+	outcomeStale  cacheOutcome = "STALE"
+	outcomeMiss   cacheOutcome = "MISS"
+	outcomeBypass cacheOutcome = "BYPASS"
+)
+
+// statusHeaders returns the names of the headers reporting the cache status,
+// which must not be stored along with responses.
+func (cfg *config) statusHeaders() []string {
+	var names []string
+	if cfg.cacheStatusName != "" {
+		names = append(names, "Cache-Status")
+	}
+	if cfg.xCacheHeader != "" {
+		names = append(names, cfg.xCacheHeader)
+	}
+	if len(names) > 0 {
+		names = append(names, "Age")
+	}
+	return names
+}
+
+// setCacheStatus reports outcome in the configured status headers, and the age of
+// cache, the entry served, if any.
+func (m *middleware) setCacheStatus(c *gin.Context, outcome cacheOutcome, cache *responseCache) {
+	if m.cfg.cacheStatusFilter != nil && !m.cfg.cacheStatusFilter(c) {
+		return
+	}
+
+	h := c.Writer.Header()
+	if m.cfg.xCacheHeader != "" {
+		h.Set(m.cfg.xCacheHeader, string(outcome))
+	}
+	if m.cfg.cacheStatusName != "" {
+		h.Set("Cache-Status", cacheStatus(m.cfg.cacheStatusName, outcome, cache, time.Now()))
+	}
+	if cache != nil && (m.cfg.xCacheHeader != "" || m.cfg.cacheStatusName != "") {
+		age := max(time.Since(cache.Stored), 0)
+		h.Set("Age", strconv.FormatInt(int64(age/time.Second), 10))
+	}
+}
+
+// cacheStatus formats an RFC 9211 Cache-Status header value. Stale hits carry a
+// negative ttl, the time since the entry expired.
+func cacheStatus(name string, outcome cacheOutcome, cache *responseCache, now time.Time) string {
+	var b strings.Builder
+	b.WriteString(name)
+	switch outcome {
+	case outcomeHit, outcomeStale:
+		b.WriteString("; hit")
+		if cache != nil && !cache.Expires.IsZero() {
+			ttl := cache.Expires.Sub(now).Truncate(time.Second)
+			b.WriteString("; ttl=" + strconv.FormatInt(int64(ttl/time.Second), 10))
+		}
+	case outcomeMiss:
+		b.WriteString("; fwd=uri-miss")
+	case outcomeBypass:
+		b.WriteString("; fwd=bypass")
+	}
+	return b.String()
+}
@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXCache(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Minute), WithXCache("")))
+	router.GET("/page", func(c *gin.Context) {
+		c.String(200, "page")
+	})
+	router.POST("/page", func(c *gin.Context) {
+		c.String(200, "posted")
+	})
+
+	w1 := performRequest("GET", "/page", router)
+	assert.Equal(t, "MISS", w1.Header().Get("X-Cache"))
+	assert.Empty(t, w1.Header().Get("Age"))
+
+	time.Sleep(time.Millisecond * 1100)
+	w2 := performRequest("GET", "/page", router)
+	assert.Equal(t, "HIT", w2.Header().Get("X-Cache"))
+	assert.Equal(t, "1", w2.Header().Get("Age"))
+
+	w3 := performRequest("POST", "/page", router)
+	assert.Equal(t, "BYPASS", w3.Header().Get("X-Cache"))
+}
+
+func TestXCacheStale(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/page", Decorate(store, func(c *gin.Context) {
+		c.String(200, "page")
+	}, WithExpire(time.Second), WithStaleWhileRevalidate(time.Minute), WithXCache("X-Cache")))
+
+	performRequest("GET", "/page", router)
+	time.Sleep(time.Millisecond * 1100)
+	w := performRequest("GET", "/page", router)
+
+	assert.Equal(t, "STALE", w.Header().Get("X-Cache"))
+	assert.Equal(t, "page", w.Body.String())
+}
+
+func TestCacheStatus(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Minute), WithCacheStatus("gin"), WithCacheStatusFilter(func(c *gin.Context) bool {
+		return c.Request.URL.Path != "/public"
+	})))
+	router.GET("/page", func(c *gin.Context) {
+		c.String(200, "page")
+	})
+	router.DELETE("/page", func(c *gin.Context) {
+		c.Status(204)
+	})
+	router.GET("/public", func(c *gin.Context) {
+		c.String(200, "public")
+	})
+
+	assert.Equal(t, "gin; fwd=uri-miss", performRequest("GET", "/page", router).Header().Get("Cache-Status"))
+	assert.Equal(t, "gin; hit", performRequest("GET", "/page", router).Header().Get("Cache-Status"))
+	assert.Equal(t, "gin; fwd=bypass", performRequest("DELETE", "/page", router).Header().Get("Cache-Status"))
+
+	performRequest("GET", "/public", router)
+	w := performRequest("GET", "/public", router)
+	assert.Empty(t, w.Header().Get("Cache-Status"))
+	assert.Empty(t, w.Header().Get("Age"))
+}
+
+func TestCacheStatusValue(t *testing.T) {
+	now := time.Now()
+	entry := &responseCache{Stored: now.Add(-time.Minute), Expires: now.Add(30 * time.Second)}
+	assert.Equal(t, "edge; hit; ttl=30", cacheStatus("edge", outcomeHit, entry, now))
+
+	entry.Expires = now.Add(-10 * time.Second)
+	assert.Equal(t, "edge; hit; ttl=-10", cacheStatus("edge", outcomeStale, entry, now))
+	assert.Equal(t, "edge; fwd=uri-miss", cacheStatus("edge", outcomeMiss, nil, now))
+	assert.Equal(t, "edge; fwd=bypass", cacheStatus("edge", outcomeBypass, nil, now))
+}
+
+// wrappedWriter stands for middleware that wraps the response writer.
+type wrappedWriter struct {
+	gin.ResponseWriter
+}
+
+func TestXCacheSkip(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Minute), WithXCache(""), WithCacheStatus("edge")))
+	router.GET("/skip", Skip(), func(c *gin.Context) {
+		c.String(200, "skipped")
+	})
+	router.GET("/wrapped", func(c *gin.Context) {
+		c.Writer = wrappedWriter{c.Writer}
+	}, Skip(), func(c *gin.Context) {
+		c.String(200, "wrapped")
+	})
+	decorated := gin.New()
+	decorated.GET("/decorated", Skip(), Decorate(store, func(c *gin.Context) {
+		c.String(200, "decorated")
+	}, WithXCache(""), WithCacheStatus("edge")))
+
+	for _, w := range []*httptest.ResponseRecorder{
+		performRequest("GET", "/skip", router),
+		performRequest("GET", "/wrapped", router),
+		performRequest("GET", "/decorated", decorated),
+	} {
+		assert.Equal(t, "BYPASS", w.Header().Get("X-Cache"), w.Body.String())
+		assert.Equal(t, "edge; fwd=bypass", w.Header().Get("Cache-Status"), w.Body.String())
+	}
+}
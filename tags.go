@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+)
+
+const tagsKey = "gincontrib.cache.tags"
+
+// Tag attaches surrogate keys to the response being cached for the current
+// request, so that InvalidateTags can purge it later. Handlers may also list
+// space-separated tags in a Surrogate-Key response header.
+func Tag(c *gin.Context, tags ...string) {
+	existing, _ := c.Get(tagsKey)
+	current, _ := existing.([]string)
+	c.Set(tagsKey, append(slices.Clip(current), tags...))
+}
+
+// responseTags returns the tags attached to the response through Tag or the Surrogate-Key header.
+func responseTags(c *gin.Context, writer *cachedWriter) []string {
+	tags := c.GetStringSlice(tagsKey)
+	for _, value := range writer.Header().Values("Surrogate-Key") {
+		tags = append(tags, strings.Fields(value)...)
+	}
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+// tagKey returns the store key of the generation of a tag.
+func tagKey(tag string) string {
+	return generateCacheKey(PageCachePrefix+".tag", tag)
+}
+
+// InvalidateTags purges every cached entry tagged with any of tags, on any store
+// implementation. Entries record the generation of their tags when stored, so
+// bumping the generations makes them unreachable; they expire from the store on
+// their own.
+func InvalidateTags(store persistence.CacheStore, tags ...string) error {
+	var errs []error
+	for _, tag := range tags {
+		if err := bumpGeneration(store, tagKey(tag)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
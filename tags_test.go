@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidateTags(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Minute)))
+	router.GET("/products/42", func(c *gin.Context) {
+		Tag(c, "product-42")
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	})
+	router.GET("/categories/1", func(c *gin.Context) {
+		c.Header("Surrogate-Key", "category-1 product-42")
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	})
+	router.GET("/products/7", func(c *gin.Context) {
+		Tag(c, "product-7")
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	})
+
+	p42 := performRequest("GET", "/products/42", router)
+	cat := performRequest("GET", "/categories/1", router)
+	p7 := performRequest("GET", "/products/7", router)
+	assert.Equal(t, p42.Body.String(), performRequest("GET", "/products/42", router).Body.String())
+
+	assert.NoError(t, InvalidateTags(store, "product-42"))
+
+	assert.NotEqual(t, p42.Body.String(), performRequest("GET", "/products/42", router).Body.String())
+	assert.NotEqual(t, cat.Body.String(), performRequest("GET", "/categories/1", router).Body.String())
+	assert.Equal(t, p7.Body.String(), performRequest("GET", "/products/7", router).Body.String())
+
+	// Invalidating unknown tags is not an error
+	assert.NoError(t, InvalidateTags(store, "unknown"))
+}
+
+func TestInvalidateTagsConcurrent(t *testing.T) {
+	store := newDelayStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Minute)))
+	router.GET("/a/:id", func(c *gin.Context) {
+		Tag(c, "a")
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	})
+
+	const n = 60
+	bodies := make([]string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bodies[i] = performRequest("GET", fmt.Sprintf("/a/%d", i), router).Body.String()
+		}(i)
+	}
+	wg.Wait()
+
+	assert.NoError(t, InvalidateTags(store, "a"))
+
+	for i := 0; i < n; i++ {
+		assert.NotEqual(t, bodies[i], performRequest("GET", fmt.Sprintf("/a/%d", i), router).Body.String())
+	}
+}
+
+// failingGenerationStore fails to read generations, as an unreachable store would.
+type failingGenerationStore struct {
+	*persistence.InMemoryStore
+}
+
+func (s failingGenerationStore) Get(key string, value any) error {
+	if _, ok := value.(*uint64); ok {
+		return errors.New("store unavailable")
+	}
+	return s.InMemoryStore.Get(key, value)
+}
+
+func TestTaggedEntryNotStoredWithoutGeneration(t *testing.T) {
+	store := failingGenerationStore{persistence.NewInMemoryStore(60 * time.Second)}
+
+	router := gin.New()
+	router.GET("/tagged", Decorate(store, func(c *gin.Context) {
+		Tag(c, "product-42")
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}))
+
+	w1 := performRequest("GET", "/tagged", router)
+	w2 := performRequest("GET", "/tagged", router)
+
+	assert.NotEqual(t, w1.Body.String(), w2.Body.String())
+}
+
+func TestInvalidateTagsDuringRender(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	var slow atomic.Bool
+	rendering, invalidated := make(chan struct{}), make(chan struct{})
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Minute)))
+	router.GET("/p", func(c *gin.Context) {
+		Tag(c, "p")
+		body := fmt.Sprint(time.Now().UnixNano())
+		if slow.CompareAndSwap(true, false) {
+			close(rendering)
+			<-invalidated
+		}
+		c.String(200, body)
+	})
+
+	// The handler renders the old body, then the tag is invalidated before it returns
+	slow.Store(true)
+	done := make(chan string)
+	go func() {
+		done <- performRequest("GET", "/p", router).Body.String()
+	}()
+	<-rendering
+	assert.NoError(t, InvalidateTags(store, "p"))
+	close(invalidated)
+	stale := <-done
+
+	w1 := performRequest("GET", "/p", router)
+	assert.NotEqual(t, stale, w1.Body.String())
+	assert.Equal(t, w1.Body.String(), performRequest("GET", "/p", router).Body.String())
+}
+
+func TestInvalidateTagsEvicted(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.Use(New(store, WithExpire(time.Minute)))
+	router.GET("/p", func(c *gin.Context) {
+		Tag(c, "p")
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	})
+
+	w1 := performRequest("GET", "/p", router)
+	assert.NoError(t, InvalidateTags(store, "p"))
+	// The store dropping the bumped generation doesn't bring the entry back
+	assert.NoError(t, store.Delete(tagKey("p")))
+
+	assert.NotEqual(t, w1.Body.String(), performRequest("GET", "/p", router).Body.String())
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"net/http"
+	"net/textproto"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// varyHeaders returns the canonical names of the request headers listed in the
+// Vary header of h. ok is false for "Vary: *", whose responses can't be cached.
+func varyHeaders(h http.Header) (names []string, ok bool) {
+	for _, value := range h.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return nil, false
+			}
+			if name != "" {
+				names = append(names, textproto.CanonicalMIMEHeaderKey(name))
+			}
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names), true
+}
+
+// variantKey returns the key of the variant of key selected by the given request headers.
+func variantKey(key string, names []string, header http.Header) string {
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteString(":")
+		b.WriteString(strings.Join(header.Values(name), ","))
+		b.WriteString("\n")
+	}
+	return generateCacheKey(key, b.String())
+}
+
+// varies reports whether the response was negotiated on request headers.
+func (r *responseCache) varies() bool {
+	return len(r.Header.Values("Vary")) > 0
+}
+
+// lookupEntry reads the entry matching the request into cache. Responses that
+// vary on request headers are stored under variant keys, with an index entry at
+// key listing the headers they vary on.
+func (m *middleware) lookupEntry(c *gin.Context, key string, cache *responseCache) bool {
+	if !m.lookup(key, cache) {
+		return false
+	}
+	if len(cache.Variants) == 0 {
+		return m.current(cache)
+	}
+	var variant responseCache
+	found := m.lookup(variantKey(key, cache.Variants, c.Request.Header), &variant)
+	*cache = variant
+	return found && m.current(cache)
+}
+
+// storeVariant points writer at the variant key of its response when the handler
+// set a Vary header. It returns the index entry to store at the base key, or nil.
+func (m *middleware) storeVariant(c *gin.Context, writer *cachedWriter) *responseCache {
+	names, ok := varyHeaders(writer.Header())
+	if !ok {
+		writer.discard()
+		return nil
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	writer.key = variantKey(writer.key, names, c.Request.Header)
+	return &responseCache{Variants: names}
+}
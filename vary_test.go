@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVaryHeaders(t *testing.T) {
+	names, ok := varyHeaders(http.Header{"Vary": {"accept-language, Accept", "Accept"}})
+	assert.True(t, ok)
+	assert.Equal(t, []string{"Accept", "Accept-Language"}, names)
+
+	names, ok = varyHeaders(http.Header{})
+	assert.True(t, ok)
+	assert.Empty(t, names)
+
+	_, ok = varyHeaders(http.Header{"Vary": {"Accept, *"}})
+	assert.False(t, ok)
+}
+
+func TestVariantKey(t *testing.T) {
+	names := []string{"Accept-Language"}
+	en := variantKey("key", names, http.Header{"Accept-Language": {"en"}})
+	fr := variantKey("key", names, http.Header{"Accept-Language": {"fr"}})
+
+	assert.NotEqual(t, en, fr)
+	assert.Equal(t, en, variantKey("key", names, http.Header{"Accept-Language": {"en"}, "Accept": {"text/html"}}))
+}
+
+func TestCachePageVary(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	calls := 0
+	router := gin.New()
+	router.GET("/vary", CachePage(store, time.Minute, func(c *gin.Context) {
+		calls++
+		c.Header("Vary", "Accept-Language")
+		c.String(200, c.GetHeader("Accept-Language")+" "+fmt.Sprint(time.Now().UnixNano()))
+	}))
+
+	en := http.Header{"Accept-Language": {"en"}}
+	fr := http.Header{"Accept-Language": {"fr"}}
+	w1 := performRequestWithHeader("GET", "/vary", en, router)
+	w2 := performRequestWithHeader("GET", "/vary", fr, router)
+	w3 := performRequestWithHeader("GET", "/vary", en, router)
+	w4 := performRequestWithHeader("GET", "/vary", fr, router)
+
+	assert.NotEqual(t, w1.Body.String(), w2.Body.String())
+	assert.Equal(t, w1.Body.String(), w3.Body.String())
+	assert.Equal(t, w2.Body.String(), w4.Body.String())
+	assert.Equal(t, 2, calls)
+}
+
+func TestCachePageVaryStar(t *testing.T) {
+	store := persistence.NewInMemoryStore(60 * time.Second)
+
+	router := gin.New()
+	router.GET("/vary", CachePage(store, time.Minute, func(c *gin.Context) {
+		c.Header("Vary", "*")
+		c.String(200, fmt.Sprint(time.Now().UnixNano()))
+	}))
+
+	w1 := performRequest("GET", "/vary", router)
+	time.Sleep(time.Millisecond * 5)
+	w2 := performRequest("GET", "/vary", router)
+
+	assert.NotEqual(t, w1.Body.String(), w2.Body.String())
+}